@@ -0,0 +1,255 @@
+package goo
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a CircuitBreaker
+type BreakerState int
+
+const (
+	// BreakerClosed lets every request through and counts the failures
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects every request until OpenTimeout has passed
+	BreakerOpen
+	// BreakerHalfOpen lets a few probe requests through to decide
+	// whether to close or open again
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	default:
+		return "half-open"
+	}
+}
+
+// BreakerConfig configures a CircuitBreaker
+type BreakerConfig struct {
+	// Name labels the metrics of the breaker
+	Name string
+	// Window is the period the failure rate is computed over, 10s by default
+	Window time.Duration
+	// MinRequests in the window before the breaker may open, 20 by default
+	MinRequests int
+	// FailureRate opens the breaker when reached, 0.5 by default
+	FailureRate float64
+	// OpenTimeout is how long the breaker stays open, 30s by default
+	OpenTimeout time.Duration
+	// HalfOpenRequests is the number of probes that must succeed
+	// to close the breaker again, 1 by default
+	HalfOpenRequests int
+	// IsFailure tells whether a handled request failed, by default
+	// when it collected an error of 5xx status or responded with one
+	IsFailure func(*Context) bool
+}
+
+const breakerBuckets = 10
+
+type breakerBucket struct {
+	start     time.Time
+	successes int
+	failures  int
+}
+
+// CircuitBreaker stops calling the handlers behind it while they keep
+// failing, so requests fail fast with 503 instead of piling up on a
+// slow dependency
+type CircuitBreaker struct {
+	config   BreakerConfig
+	mu       sync.Mutex
+	state    BreakerState
+	openedAt time.Time
+	buckets  [breakerBuckets]breakerBucket
+	probes   int // in flight while half-open
+	passed   int // successful probes
+	// generation changes with the state, so requests admitted in an
+	// earlier state don't count as probes
+	generation uint64
+	counts     map[string]int64
+	now        func() time.Time
+}
+
+// defaultIsFailure counts server errors only, a 404 or an invalid body
+// says nothing about the health of the handlers
+func defaultIsFailure(c *Context) bool {
+	for _, e := range c.Errors {
+		if c.errorStatus(e) >= http.StatusInternalServerError {
+			return true
+		}
+	}
+	return c.StatusCode >= http.StatusInternalServerError
+}
+
+func NewCircuitBreaker(config BreakerConfig) *CircuitBreaker {
+	if config.Window <= 0 {
+		config.Window = 10 * time.Second
+	}
+	if config.MinRequests <= 0 {
+		config.MinRequests = 20
+	}
+	if config.FailureRate <= 0 {
+		config.FailureRate = 0.5
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = 30 * time.Second
+	}
+	if config.HalfOpenRequests <= 0 {
+		config.HalfOpenRequests = 1
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = defaultIsFailure
+	}
+	return &CircuitBreaker{config: config, counts: make(map[string]int64), now: time.Now}
+}
+
+// State returns the current state of the breaker
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	return b.state
+}
+
+// advance moves an open breaker whose timeout passed to half-open,
+// it must be called with mu held
+func (b *CircuitBreaker) advance() {
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.config.OpenTimeout {
+		b.state = BreakerHalfOpen
+		b.probes, b.passed = 0, 0
+		b.generation++
+	}
+}
+
+// allow reports whether a request may pass, or how long to retry after.
+// The generation it returns is passed to record with the result
+func (b *CircuitBreaker) allow() (uint64, bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	switch b.state {
+	case BreakerOpen:
+		b.counts["rejected"]++
+		return b.generation, false, b.config.OpenTimeout - b.now().Sub(b.openedAt)
+	case BreakerHalfOpen:
+		if b.probes+b.passed >= b.config.HalfOpenRequests {
+			b.counts["rejected"]++
+			return b.generation, false, time.Second
+		}
+		b.probes++
+	}
+	return b.generation, true, 0
+}
+
+func (b *CircuitBreaker) bucket(now time.Time) *breakerBucket {
+	size := b.config.Window / breakerBuckets
+	start := now.Truncate(size)
+	bucket := &b.buckets[int(start.UnixNano()/int64(size))%breakerBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = breakerBucket{start: start}
+	}
+	return bucket
+}
+
+func (b *CircuitBreaker) record(generation uint64, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if failed {
+		b.counts["failure"]++
+	} else {
+		b.counts["success"]++
+	}
+
+	if generation != b.generation {
+		return // admitted before the state changed
+	}
+	now := b.now()
+	if b.state == BreakerHalfOpen {
+		b.probes--
+		if failed {
+			b.open(now)
+			return
+		}
+		if b.passed++; b.passed >= b.config.HalfOpenRequests {
+			b.state = BreakerClosed
+			b.buckets = [breakerBuckets]breakerBucket{}
+			b.generation++
+		}
+		return
+	}
+
+	bucket := b.bucket(now)
+	if failed {
+		bucket.failures++
+	} else {
+		bucket.successes++
+	}
+	var successes, failures int
+	for _, bucket := range b.buckets {
+		if now.Sub(bucket.start) < b.config.Window {
+			successes += bucket.successes
+			failures += bucket.failures
+		}
+	}
+	total := successes + failures
+	if total >= b.config.MinRequests && float64(failures)/float64(total) >= b.config.FailureRate {
+		b.open(now)
+	}
+}
+
+func (b *CircuitBreaker) open(now time.Time) {
+	b.state = BreakerOpen
+	b.openedAt = now
+	b.counts["opened"]++
+	b.generation++
+}
+
+// Handler runs the handlers behind it while the breaker is closed
+// and sheds the requests while it is open
+func (b *CircuitBreaker) Handler() HandlerFunc {
+	return func(c *Context) {
+		generation, ok, retryAfter := b.allow()
+		if !ok {
+			shed(c, retryAfter)
+			return
+		}
+		failed := true // a panic counts as a failure
+		defer func() { b.record(generation, failed) }()
+		c.Next()
+		failed = b.config.IsFailure(c)
+	}
+}
+
+// shed rejects the request with 503 and a Retry-After in whole seconds
+func shed(c *Context, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.SetHeader("Retry-After", strconv.Itoa(seconds))
+	c.Fail(http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable))
+}
+
+func (b *CircuitBreaker) metrics() []metricSample {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	labels := map[string]string{"name": b.config.Name}
+	samples := []metricSample{
+		{"goo_circuit_breaker_state", "State of the circuit breaker, 0 closed, 1 open, 2 half-open", "gauge", labels, float64(b.state)},
+		{"goo_circuit_breaker_opened_total", "Times the circuit breaker opened", "counter", labels, float64(b.counts["opened"])},
+	}
+	for _, result := range []string{"success", "failure", "rejected"} {
+		samples = append(samples, metricSample{"goo_circuit_breaker_requests_total", "Requests by result",
+			"counter", map[string]string{"name": b.config.Name, "result": result}, float64(b.counts[result])})
+	}
+	return samples
+}
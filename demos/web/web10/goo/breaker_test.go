@@ -0,0 +1,188 @@
+package goo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(1000, 0)
+	breaker := NewCircuitBreaker(BreakerConfig{Name: "mysql", MinRequests: 4, OpenTimeout: 5 * time.Second, HalfOpenRequests: 2})
+	breaker.now = func() time.Time { return now }
+
+	failing := true
+	r := New()
+	r.Use(breaker.Handler())
+	r.GET("/todo", func(c *Context) {
+		if failing {
+			c.Fail(http.StatusInternalServerError, "db timeout")
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/todo", nil))
+		return w
+	}
+
+	for i := 0; i < 4; i++ {
+		if w := get(); w.Code != http.StatusInternalServerError {
+			t.Fatalf("unexpected status %d", w.Code)
+		}
+	}
+	if breaker.State() != BreakerOpen {
+		t.Fatalf("unexpected state %s", breaker.State())
+	}
+	now = now.Add(2 * time.Second)
+	if w := get(); w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "3" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+
+	now = now.Add(3 * time.Second)
+	if breaker.State() != BreakerHalfOpen {
+		t.Fatalf("unexpected state %s", breaker.State())
+	}
+	if w := get(); w.Code != http.StatusInternalServerError || breaker.State() != BreakerOpen {
+		t.Fatalf("a failed probe should open the breaker again, %d %s", w.Code, breaker.State())
+	}
+
+	now = now.Add(5 * time.Second)
+	failing = false
+	get()
+	if breaker.State() != BreakerHalfOpen {
+		t.Fatalf("unexpected state %s", breaker.State())
+	}
+	get()
+	if breaker.State() != BreakerClosed {
+		t.Fatalf("unexpected state %s", breaker.State())
+	}
+
+	w := httptest.NewRecorder()
+	r.GET("/metrics", Metrics(breaker))
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	for _, line := range []string{
+		`goo_circuit_breaker_state{name="mysql"} 0`,
+		`goo_circuit_breaker_opened_total{name="mysql"} 2`,
+		`goo_circuit_breaker_requests_total{name="mysql",result="rejected"} 1`,
+	} {
+		if !strings.Contains(w.Body.String(), line+"\n") {
+			t.Fatalf("metrics %q don't contain %q", w.Body.String(), line)
+		}
+	}
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	now := time.Unix(1000, 0)
+	breaker := NewCircuitBreaker(BreakerConfig{MinRequests: 4, Window: 10 * time.Second})
+	breaker.now = func() time.Time { return now }
+	for i := 0; i < 3; i++ {
+		breaker.record(0, true)
+	}
+	// the failures left the window
+	now = now.Add(11 * time.Second)
+	breaker.record(0, true)
+	breaker.record(0, false)
+	if breaker.State() != BreakerClosed {
+		t.Fatalf("unexpected state %s", breaker.State())
+	}
+}
+
+func TestCircuitBreakerLateResult(t *testing.T) {
+	now := time.Unix(1000, 0)
+	breaker := NewCircuitBreaker(BreakerConfig{MinRequests: 2, OpenTimeout: 5 * time.Second})
+	breaker.now = func() time.Time { return now }
+
+	slow, _, _ := breaker.allow() // admitted while closed
+	for i := 0; i < 2; i++ {
+		generation, _, _ := breaker.allow()
+		breaker.record(generation, true)
+	}
+	now = now.Add(5 * time.Second)
+	if breaker.State() != BreakerHalfOpen {
+		t.Fatalf("unexpected state %s", breaker.State())
+	}
+	// the slow request isn't a probe and can't close the breaker
+	breaker.record(slow, false)
+	if breaker.State() != BreakerHalfOpen || breaker.probes != 0 {
+		t.Fatalf("unexpected state %s with %d probes", breaker.State(), breaker.probes)
+	}
+	probe, ok, _ := breaker.allow()
+	if !ok {
+		t.Fatalf("the probe should pass")
+	}
+	breaker.record(probe, false)
+	if breaker.State() != BreakerClosed {
+		t.Fatalf("unexpected state %s", breaker.State())
+	}
+}
+
+func TestCircuitBreakerClientErrors(t *testing.T) {
+	breaker := NewCircuitBreaker(BreakerConfig{MinRequests: 2})
+	r := New()
+	r.Use(breaker.Handler())
+	r.GET("/todo/:id", func(c *Context) {
+		c.Error(errNotFound).SetStatus(http.StatusNotFound)
+	})
+	r.GET("/bind", func(c *Context) {
+		c.Fail(http.StatusBadRequest, "invalid title")
+	})
+	for _, path := range []string{"/todo/1", "/bind", "/todo/2", "/bind"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	}
+	if breaker.State() != BreakerClosed {
+		t.Fatalf("client errors shouldn't open the breaker, state %s", breaker.State())
+	}
+}
+
+func TestBulkhead(t *testing.T) {
+	bulkhead := NewBulkhead(BulkheadConfig{Name: "db", MaxConcurrent: 1, MaxWaiting: 1, MaxWait: time.Second, RetryAfter: 2 * time.Second})
+	release := make(chan struct{})
+	r := New()
+	slow := r.Group("/slow")
+	slow.Use(bulkhead.Handler())
+	slow.GET("", func(c *Context) {
+		<-release
+		c.String(http.StatusOK, "ok")
+	})
+
+	codes := make(chan int, 3)
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+			if w.Code == http.StatusServiceUnavailable && w.Header().Get("Retry-After") != "2" {
+				t.Errorf("unexpected Retry-After %q", w.Header().Get("Retry-After"))
+			}
+			codes <- w.Code
+		}()
+	}
+	// one request is handled, one waits and one is shed
+	if code := <-codes; code != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected status %d", code)
+	}
+
+	w := httptest.NewRecorder()
+	Metrics(bulkhead)(&Context{Writer: w})
+	if !strings.Contains(w.Body.String(), `goo_bulkhead_in_flight{name="db"} 1`) ||
+		!strings.Contains(w.Body.String(), `goo_bulkhead_waiting{name="db"} 1`) {
+		t.Fatalf("unexpected metrics %q", w.Body.String())
+	}
+
+	close(release)
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Fatalf("unexpected status %d", code)
+		}
+	}
+}
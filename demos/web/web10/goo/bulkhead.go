@@ -0,0 +1,101 @@
+package goo
+
+import (
+	"sync"
+	"time"
+)
+
+// BulkheadConfig configures a Bulkhead
+type BulkheadConfig struct {
+	// Name labels the metrics of the bulkhead
+	Name string
+	// MaxConcurrent requests are handled at once, 10 by default
+	MaxConcurrent int
+	// MaxWaiting requests queue for a free slot for up to MaxWait,
+	// more are shed at once. Nothing queues when MaxWait is 0
+	MaxWaiting int
+	MaxWait    time.Duration
+	// RetryAfter is sent to shed requests, 1s by default
+	RetryAfter time.Duration
+}
+
+// Bulkhead limits the requests handled concurrently by a route group,
+// so a slow dependency of one group can't take up every goroutine.
+// Requests over the limit are shed with 503 and Retry-After
+type Bulkhead struct {
+	config   BulkheadConfig
+	slots    chan struct{}
+	mu       sync.Mutex
+	waiting  int
+	rejected int64
+}
+
+func NewBulkhead(config BulkheadConfig) *Bulkhead {
+	if config.MaxConcurrent <= 0 {
+		config.MaxConcurrent = 10
+	}
+	if config.RetryAfter <= 0 {
+		config.RetryAfter = time.Second
+	}
+	return &Bulkhead{config: config, slots: make(chan struct{}, config.MaxConcurrent)}
+}
+
+// acquire takes a slot, waiting in the queue when there is room
+func (b *Bulkhead) acquire(c *Context) bool {
+	select {
+	case b.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	b.mu.Lock()
+	if b.waiting >= b.config.MaxWaiting || b.config.MaxWait <= 0 {
+		b.rejected++
+		b.mu.Unlock()
+		return false
+	}
+	b.waiting++
+	b.mu.Unlock()
+
+	timer := time.NewTimer(b.config.MaxWait)
+	defer timer.Stop()
+	acquired := false
+	select {
+	case b.slots <- struct{}{}:
+		acquired = true
+	case <-timer.C:
+	case <-c.Req.Context().Done():
+	}
+
+	b.mu.Lock()
+	b.waiting--
+	if !acquired {
+		b.rejected++
+	}
+	b.mu.Unlock()
+	return acquired
+}
+
+// Handler limits the concurrency of the handlers behind it
+func (b *Bulkhead) Handler() HandlerFunc {
+	return func(c *Context) {
+		if !b.acquire(c) {
+			shed(c, b.config.RetryAfter)
+			return
+		}
+		defer func() { <-b.slots }()
+		c.Next()
+	}
+}
+
+func (b *Bulkhead) metrics() []metricSample {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	labels := map[string]string{"name": b.config.Name}
+	return []metricSample{
+		{"goo_bulkhead_in_flight", "Requests being handled", "gauge", labels, float64(len(b.slots))},
+		{"goo_bulkhead_waiting", "Requests waiting for a slot", "gauge", labels, float64(b.waiting)},
+		{"goo_bulkhead_limit", "Requests handled at once at most", "gauge", labels, float64(b.config.MaxConcurrent)},
+		{"goo_bulkhead_rejected_total", "Requests shed with 503", "counter", labels, float64(b.rejected)},
+	}
+}
@@ -0,0 +1,454 @@
+package goo
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheConfig configures a response Cache
+type CacheConfig struct {
+	// TTL is how long responses are served from the cache, 1 minute by default
+	TTL time.Duration
+	// MaxBytes limits the size of the cached bodies and headers, 64 MB by default
+	MaxBytes int64
+	// MaxEntries limits the number of cached responses, 0 is unlimited
+	MaxEntries int
+	// Key identifies the response of a request, method, host and
+	// request uri by default
+	Key func(*Context) string
+}
+
+// Cache stores successful GET and HEAD responses in memory, evicting the
+// least recently used ones. Responses setting cookies, sending
+// Cache-Control no-store or private or Vary: * aren't cached, neither
+// are responses to requests with Authorization unless they are
+// Cache-Control public. The request headers named by Vary are part of
+// the key. Pages using Context.CSPNonce shouldn't be cached since the
+// nonce would be replayed
+type Cache struct {
+	config  CacheConfig
+	mu      sync.Mutex
+	lru     *list.List               // front is the most recently used
+	entries map[string]*list.Element // of *cacheEntry
+	tags    map[string]map[string]bool
+	varies  map[string]*cacheVary // by the key of CacheConfig.Key
+	size    int64
+	flight  flightGroup
+}
+
+type cacheEntry struct {
+	key      string
+	base     string // key without the varying request headers
+	vary     []string
+	public   bool
+	header   http.Header // set by the handler
+	body     []byte
+	modified time.Time
+	expires  time.Time
+	tags     []string
+	size     int64
+}
+
+// cacheVary holds the Vary header names of the entries sharing a key
+type cacheVary struct {
+	names   []string
+	entries int
+}
+
+func defaultCacheKey(c *Context) string {
+	return c.Req.Method + " " + c.Req.Host + c.Req.URL.RequestURI()
+}
+
+func NewCache(config CacheConfig) *Cache {
+	if config.TTL <= 0 {
+		config.TTL = time.Minute
+	}
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = 64 << 20
+	}
+	if config.Key == nil {
+		config.Key = defaultCacheKey
+	}
+	return &Cache{
+		config:  config,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+		tags:    make(map[string]map[string]bool),
+		varies:  make(map[string]*cacheVary),
+	}
+}
+
+// variantKey adds the values of the request headers named by the Vary
+// header of the responses stored for base
+func (cache *Cache) variantKey(c *Context, base string) string {
+	cache.mu.Lock()
+	v := cache.varies[base]
+	cache.mu.Unlock()
+	if v == nil {
+		return base
+	}
+	return varyKey(c, base, v.names)
+}
+
+func varyKey(c *Context, base string, names []string) string {
+	key := base
+	for _, name := range names {
+		key += "\n" + name + ": " + strings.Join(c.Req.Header.Values(name), ", ")
+	}
+	return key
+}
+
+// varyNames returns the header names of Vary, ok is false for Vary: *
+func varyNames(header http.Header) (names []string, ok bool) {
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" {
+				return nil, false
+			}
+			if name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names, true
+}
+
+// CacheTags tags the response for Cache.Invalidate
+func (c *Context) CacheTags(tags ...string) {
+	c.cacheTags = append(c.cacheTags, tags...)
+}
+
+func (cache *Cache) get(key string) *cacheEntry {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	el, ok := cache.entries[key]
+	if !ok {
+		return nil
+	}
+	e := el.Value.(*cacheEntry)
+	if time.Now().After(e.expires) {
+		cache.remove(el)
+		return nil
+	}
+	cache.lru.MoveToFront(el)
+	return e
+}
+
+func (cache *Cache) add(e *cacheEntry) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if e.size > cache.config.MaxBytes {
+		return
+	}
+	if el, ok := cache.entries[e.key]; ok {
+		cache.remove(el)
+	}
+	cache.entries[e.key] = cache.lru.PushFront(e)
+	cache.size += e.size
+	v := cache.varies[e.base]
+	if v == nil {
+		v = &cacheVary{}
+		cache.varies[e.base] = v
+	}
+	v.names = e.vary
+	v.entries++
+	for _, tag := range e.tags {
+		if cache.tags[tag] == nil {
+			cache.tags[tag] = make(map[string]bool)
+		}
+		cache.tags[tag][e.key] = true
+	}
+	for cache.size > cache.config.MaxBytes ||
+		cache.config.MaxEntries > 0 && cache.lru.Len() > cache.config.MaxEntries {
+		cache.remove(cache.lru.Back())
+	}
+}
+
+// remove must be called with mu held
+func (cache *Cache) remove(el *list.Element) {
+	e := cache.lru.Remove(el).(*cacheEntry)
+	delete(cache.entries, e.key)
+	cache.size -= e.size
+	if v := cache.varies[e.base]; v != nil {
+		if v.entries--; v.entries == 0 {
+			delete(cache.varies, e.base)
+		}
+	}
+	for _, tag := range e.tags {
+		delete(cache.tags[tag], e.key)
+		if len(cache.tags[tag]) == 0 {
+			delete(cache.tags, tag)
+		}
+	}
+}
+
+// Invalidate removes the responses tagged with any of tags
+func (cache *Cache) Invalidate(tags ...string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	for _, tag := range tags {
+		for key := range cache.tags[tag] {
+			cache.remove(cache.entries[key])
+		}
+	}
+}
+
+// Purge removes every response
+func (cache *Cache) Purge() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.lru.Init()
+	cache.entries = make(map[string]*list.Element)
+	cache.tags = make(map[string]map[string]bool)
+	cache.varies = make(map[string]*cacheVary)
+	cache.size = 0
+}
+
+// Len returns the number of cached responses
+func (cache *Cache) Len() int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.lru.Len()
+}
+
+// Handler serves cached responses and caches the responses of the
+// handlers behind it. Concurrent requests for a missing key wait for
+// the first one instead of running the handlers too. Upgrades and
+// event streams pass through, as do responses that are flushed
+func (cache *Cache) Handler() HandlerFunc {
+	return func(c *Context) {
+		if c.Method != http.MethodGet && c.Method != http.MethodHead ||
+			c.Req.Header.Get("Upgrade") != "" ||
+			strings.Contains(c.Req.Header.Get("Accept"), "text/event-stream") {
+			c.Next()
+			return
+		}
+		base := cache.config.Key(c)
+		key := cache.variantKey(c, base)
+		// responses for one user are only shared when they are public
+		auth := c.Req.Header.Get("Authorization") != ""
+		if e := cache.get(key); e != nil && (!auth || e.public) {
+			serveCached(c, e, "HIT")
+			c.Abort()
+			return
+		}
+		if auth {
+			cache.fill(c, base, auth)
+			return
+		}
+		e, leader := cache.flight.do(key, func() *cacheEntry {
+			return cache.fill(c, base, auth)
+		})
+		if leader {
+			return
+		}
+		// the first request may have learnt the key varies
+		if e != nil && e.key == varyKey(c, base, e.vary) {
+			serveCached(c, e, "HIT")
+			c.Abort()
+			return
+		}
+		// the response of the first request wasn't cacheable
+		c.Next()
+	}
+}
+
+// cacheWriter buffers the response so it can be stored before it is sent,
+// once flushed or hijacked it writes through and nothing is cached
+type cacheWriter struct {
+	http.ResponseWriter
+	status  int
+	body    bytes.Buffer
+	through bool
+}
+
+func (w *cacheWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+		if w.through {
+			w.ResponseWriter.WriteHeader(code)
+		}
+	}
+}
+
+func (w *cacheWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.through {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.body.Write(b)
+}
+
+// writeThrough sends what was buffered and stops buffering
+func (w *cacheWriter) writeThrough() {
+	if w.through {
+		return
+	}
+	w.through = true
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+		w.ResponseWriter.Write(w.body.Bytes())
+		w.body.Reset()
+	}
+}
+
+func (w *cacheWriter) Flush() {
+	w.writeThrough()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *cacheWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("goo: response writer can't be hijacked")
+	}
+	w.through = true
+	return h.Hijack()
+}
+
+func (w *cacheWriter) Written() bool {
+	return w.status != 0 || w.through
+}
+
+func (w *cacheWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// fill runs the handlers and sends their response, it returns the
+// cached entry or nil when the response isn't cacheable
+func (cache *Cache) fill(c *Context, base string, auth bool) *cacheEntry {
+	header := c.Writer.Header()
+	before := header.Clone()
+	w := &cacheWriter{ResponseWriter: c.Writer}
+	c.Writer = w
+	func() {
+		// a panicking handler leaves the response to Recovery
+		defer func() { c.Writer = w.ResponseWriter }()
+		c.Next()
+	}()
+	if w.through {
+		return nil
+	}
+	c.StatusCode = 0
+
+	if w.status == 0 {
+		// nothing written, e.g. errors left to the engine's error handler
+		return nil
+	}
+	cacheControl := header.Get("Cache-Control")
+	public := strings.Contains(cacheControl, "public")
+	vary, ok := varyNames(header)
+	if w.status != http.StatusOK || header.Get("Set-Cookie") != "" || !ok || auth && !public ||
+		strings.Contains(cacheControl, "no-store") || strings.Contains(cacheControl, "private") {
+		c.Status(w.status)
+		c.Writer.Write(w.body.Bytes())
+		return nil
+	}
+
+	now := time.Now()
+	key := varyKey(c, base, vary)
+	e := &cacheEntry{
+		key:      key,
+		base:     base,
+		vary:     vary,
+		public:   public,
+		header:   make(http.Header),
+		body:     w.body.Bytes(),
+		modified: now.Truncate(time.Second),
+		expires:  now.Add(cache.config.TTL),
+		tags:     c.cacheTags,
+	}
+	for k, v := range header {
+		if !equalValues(before[k], v) {
+			e.header[k] = v
+		}
+	}
+	if modified, err := http.ParseTime(header.Get("Last-Modified")); err == nil {
+		e.modified = modified
+	}
+	if e.header.Get("ETag") == "" {
+		sum := sha256.Sum256(e.body)
+		e.header.Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	}
+	e.size = int64(len(key) + len(e.body))
+	for k, v := range e.header {
+		e.size += int64(len(k) + len(strings.Join(v, "")))
+	}
+	cache.add(e)
+	serveCached(c, e, "MISS")
+	return e
+}
+
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// serveCached answers conditional and range requests like static files
+func serveCached(c *Context, e *cacheEntry, status string) {
+	header := c.Writer.Header()
+	for k, v := range e.header {
+		header[k] = v
+	}
+	header.Set("X-Cache", status)
+	http.ServeContent(&statusWriter{c.Writer, c}, c.Req, "", e.modified, bytes.NewReader(e.body))
+}
+
+// flightGroup collapses concurrent calls with the same key into one
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+type flight struct {
+	wg    sync.WaitGroup
+	entry *cacheEntry
+}
+
+// do calls fn unless a call for key is in flight, in which case it waits
+// for its result. leader reports whether fn was called
+func (g *flightGroup) do(key string, fn func() *cacheEntry) (entry *cacheEntry, leader bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flight)
+	}
+	if f, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		f.wg.Wait()
+		return f.entry, false
+	}
+	f := &flight{}
+	f.wg.Add(1)
+	g.calls[key] = f
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		f.wg.Done()
+	}()
+	f.entry = fn()
+	return f.entry, true
+}
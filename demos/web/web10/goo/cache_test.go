@@ -0,0 +1,237 @@
+package goo
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func serveCache(r *Engine, path string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCache(t *testing.T) {
+	var calls int32
+	cache := NewCache(CacheConfig{TTL: time.Minute})
+	r := New()
+	r.Use(cache.Handler())
+	r.GET("/students", func(c *Context) {
+		n := atomic.AddInt32(&calls, 1)
+		c.CacheTags("students")
+		c.SetHeader("Content-Type", "text/plain")
+		c.String(http.StatusOK, "students %d", n)
+	})
+	r.GET("/me", func(c *Context) {
+		atomic.AddInt32(&calls, 1)
+		c.SetCookie("seen", "1", 0, "", "", false, true)
+		c.String(http.StatusOK, "me")
+	})
+
+	w := serveCache(r, "/students")
+	if w.Body.String() != "students 1" || w.Header().Get("X-Cache") != "MISS" {
+		t.Fatalf("unexpected response %q %v", w.Body.String(), w.Header())
+	}
+	etag := w.Header().Get("ETag")
+	modified := w.Header().Get("Last-Modified")
+	if etag == "" || modified == "" {
+		t.Fatalf("unexpected headers %v", w.Header())
+	}
+
+	w = serveCache(r, "/students")
+	if w.Body.String() != "students 1" || w.Header().Get("X-Cache") != "HIT" || w.Header().Get("Content-Type") != "text/plain" {
+		t.Fatalf("unexpected response %q %v", w.Body.String(), w.Header())
+	}
+	if w = serveCache(r, "/students", "If-None-Match", etag); w.Code != http.StatusNotModified {
+		t.Fatalf("unexpected status %d", w.Code)
+	}
+	if w = serveCache(r, "/students", "If-Modified-Since", modified); w.Code != http.StatusNotModified {
+		t.Fatalf("unexpected status %d", w.Code)
+	}
+
+	cache.Invalidate("students")
+	if w = serveCache(r, "/students"); w.Body.String() != "students 2" {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+
+	serveCache(r, "/me")
+	serveCache(r, "/me")
+	if calls != 4 {
+		t.Fatalf("responses setting cookies shouldn't be cached, %d calls", calls)
+	}
+}
+
+func TestCacheLimits(t *testing.T) {
+	cache := NewCache(CacheConfig{MaxEntries: 2, TTL: 50 * time.Millisecond})
+	r := New()
+	r.Use(cache.Handler())
+	r.GET("/page/:n", func(c *Context) {
+		c.String(http.StatusOK, "page %s", c.Param("n"))
+	})
+	for i := 0; i < 3; i++ {
+		serveCache(r, fmt.Sprintf("/page/%d", i))
+	}
+	if cache.Len() != 2 {
+		t.Fatalf("unexpected len %d", cache.Len())
+	}
+	if w := serveCache(r, "/page/0"); w.Header().Get("X-Cache") != "MISS" {
+		t.Fatal("the least recently used page should be evicted")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if w := serveCache(r, "/page/2"); w.Header().Get("X-Cache") != "MISS" {
+		t.Fatal("expired pages shouldn't be served")
+	}
+
+	small := NewCache(CacheConfig{MaxBytes: 10})
+	r = New()
+	r.Use(small.Handler())
+	r.GET("/big", func(c *Context) {
+		c.String(http.StatusOK, "a body larger than ten bytes")
+	})
+	serveCache(r, "/big")
+	if small.Len() != 0 {
+		t.Fatal("responses larger than MaxBytes shouldn't be cached")
+	}
+}
+
+func TestCacheSingleflight(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	cache := NewCache(CacheConfig{})
+	r := New()
+	r.Use(cache.Handler())
+	r.GET("/slow", func(c *Context) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		c.String(http.StatusOK, "slow")
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if w := serveCache(r, "/slow"); w.Body.String() != "slow" {
+				t.Errorf("unexpected body %q", w.Body.String())
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls != 1 {
+		t.Fatalf("concurrent misses should be collapsed, %d calls", calls)
+	}
+}
+
+func TestCacheStreams(t *testing.T) {
+	cache := NewCache(CacheConfig{TTL: time.Minute})
+	release := make(chan struct{})
+	r := New()
+	r.Use(cache.Handler())
+	r.GET("/ws", func(c *Context) {
+		ws, err := c.Upgrade()
+		if err != nil {
+			return
+		}
+		defer ws.Close(CloseNormalClosure, "")
+		if messageType, data, err := ws.ReadMessage(); err == nil {
+			ws.WriteMessage(messageType, data)
+		}
+	})
+	r.GET("/events", func(c *Context) {
+		c.SSE(Event{Data: "first"})
+		<-release
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+	defer close(release)
+
+	client, status := dialTestWebSocket(t, server, "")
+	defer client.conn.Close()
+	if status != "101 Switching Protocols s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected handshake %q", status)
+	}
+	client.writeFrame(true, TextMessage, []byte("hi"))
+	if op, payload := client.readFrame(t); op != TextMessage || string(payload) != "hi" {
+		t.Fatalf("expected echo, got %d %q", op, payload)
+	}
+
+	// no Accept header, the flush must reach the client while the handler runs
+	res, err := http.Get(server.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	line, err := bufio.NewReader(res.Body).ReadString('\n')
+	if err != nil || line != "data: first\n" {
+		t.Fatalf("unexpected event %q %v", line, err)
+	}
+	if res.Header.Get("X-Cache") != "" || cache.Len() != 0 {
+		t.Fatalf("unexpected cached stream %v", res.Header)
+	}
+}
+
+func TestCacheAuthorizationAndVary(t *testing.T) {
+	var calls int32
+	cache := NewCache(CacheConfig{TTL: time.Minute})
+	r := New()
+	r.Use(cache.Handler())
+	r.GET("/todo", func(c *Context) {
+		atomic.AddInt32(&calls, 1)
+		c.Negotiate(http.StatusOK, H{"title": "learn goo"}, "application/json", "application/xml")
+	})
+	r.GET("/profile", func(c *Context) {
+		n := atomic.AddInt32(&calls, 1)
+		c.String(http.StatusOK, "%s %d", c.Req.Header.Get("Authorization"), n)
+	})
+	r.GET("/news", func(c *Context) {
+		n := atomic.AddInt32(&calls, 1)
+		c.SetHeader("Cache-Control", "public")
+		c.String(http.StatusOK, "news %d", n)
+	})
+	r.GET("/any", func(c *Context) {
+		n := atomic.AddInt32(&calls, 1)
+		c.SetHeader("Vary", "*")
+		c.String(http.StatusOK, "any %d", n)
+	})
+
+	serveCache(r, "/todo", "Accept", "application/json")
+	w := serveCache(r, "/todo", "Accept", "application/xml")
+	if w.Header().Get("X-Cache") != "MISS" || w.Header().Get("Content-Type") != "application/xml" {
+		t.Fatalf("unexpected response %q %v", w.Body.String(), w.Header())
+	}
+	w = serveCache(r, "/todo", "Accept", "application/json")
+	if w.Header().Get("X-Cache") != "HIT" || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected response %q %v", w.Body.String(), w.Header())
+	}
+
+	serveCache(r, "/profile", "Authorization", "Bearer alice")
+	if w = serveCache(r, "/profile", "Authorization", "Bearer bob"); w.Body.String() != "Bearer bob 4" {
+		t.Fatalf("unexpected response %q", w.Body.String())
+	}
+	serveCache(r, "/profile")
+	if w = serveCache(r, "/profile", "Authorization", "Bearer bob"); w.Body.String() != "Bearer bob 6" {
+		t.Fatalf("an anonymous response was served to %q", w.Body.String())
+	}
+
+	serveCache(r, "/news", "Authorization", "Bearer alice")
+	if w = serveCache(r, "/news", "Authorization", "Bearer bob"); w.Header().Get("X-Cache") != "HIT" {
+		t.Fatalf("public responses should be cached %v", w.Header())
+	}
+
+	serveCache(r, "/any")
+	if w = serveCache(r, "/any"); w.Body.String() != "any 9" {
+		t.Fatalf("Vary: * responses shouldn't be cached, got %q", w.Body.String())
+	}
+}
@@ -0,0 +1,71 @@
+package goo
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Params can be constrained with :name<constraint>, where the constraint
+// is int, uuid, alpha, alnum, a name added with RegisterConstraint or a
+// regular expression without '/'. A segment failing the constraint falls
+// through to the sibling routes, so register constrained routes before
+// an unconstrained :param at the same position
+
+// splitParam splits ":id<int>" and ":id<int>?" into "id" and "int"
+func splitParam(part string) (string, string) {
+	name := strings.TrimSuffix(part[1:], "?")
+	if i := strings.IndexByte(name, '<'); i >= 0 && strings.HasSuffix(name, ">") {
+		return name[:i], name[i+1 : len(name)-1]
+	}
+	return name, ""
+}
+
+func hasConstraint(part string) bool {
+	_, constraint := splitParam(part)
+	return part[0] == ':' && constraint != ""
+}
+
+func defaultConstraints() map[string]func(string) bool {
+	return map[string]func(string) bool{
+		"int": func(s string) bool {
+			s = strings.TrimPrefix(s, "-")
+			return s != "" && strings.Trim(s, "0123456789") == ""
+		},
+		"uuid":  isUUID,
+		"alpha": regexp.MustCompile(constraintPatterns["alpha"]).MatchString,
+		"alnum": regexp.MustCompile(constraintPatterns["alnum"]).MatchString,
+	}
+}
+
+// constraintPatterns are the expressions of the named constraints
+// that have one, for the OpenAPI document
+var constraintPatterns = map[string]string{
+	"alpha": `^[A-Za-z]+$`,
+	"alnum": `^[A-Za-z0-9]+$`,
+}
+
+// RegisterConstraint adds a named constraint for route params like
+// :code<country>, register it before the routes using it
+func (engine *Engine) RegisterConstraint(name string, match func(string) bool) {
+	engine.router.constraints[name] = match
+	for _, h := range engine.hosts {
+		h.router.constraints = engine.router.constraints
+	}
+}
+
+// compileConstraint returns the matcher of a :param<constraint> part,
+// the constraint is a registered name or else a regular expression
+// matching the whole segment. It panics on invalid expressions so
+// they are found at startup
+func (r *router) compileConstraint(part string) func(string) bool {
+	_, constraint := splitParam(part)
+	if match, ok := r.constraints[constraint]; ok {
+		return match
+	}
+	re, err := regexp.Compile("^(?:" + constraint + ")$")
+	if err != nil {
+		panic(fmt.Sprintf("goo: invalid constraint in %s: %v", part, err))
+	}
+	return re.MatchString
+}
@@ -0,0 +1,106 @@
+package goo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouteConstraints(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/todo/:id<int>", nil)
+	r.addRoute("GET", "/todo/:uuid<uuid>", nil)
+	r.addRoute("GET", "/todo/:slug<[a-z-]+>", nil)
+	r.addRoute("GET", "/todo/:name", nil)
+
+	tests := []struct {
+		path, pattern, key, value string
+	}{
+		{"/todo/42", "/todo/:id<int>", "id", "42"},
+		{"/todo/0f8fad5b-d9cb-469f-a165-70867728950e", "/todo/:uuid<uuid>", "uuid", "0f8fad5b-d9cb-469f-a165-70867728950e"},
+		{"/todo/learn-goo", "/todo/:slug<[a-z-]+>", "slug", "learn-goo"},
+		{"/todo/Learn_Goo", "/todo/:name", "name", "Learn_Goo"},
+	}
+	for _, tt := range tests {
+		n, params := r.getRoute("GET", tt.path)
+		if n == nil || n.pattern != tt.pattern {
+			t.Fatalf("unexpected route for %s: %v", tt.path, n)
+		}
+		if params[tt.key] != tt.value {
+			t.Fatalf("unexpected params for %s: %v", tt.path, params)
+		}
+	}
+}
+
+func TestRouteConstraintNotFound(t *testing.T) {
+	r := New()
+	r.GET("/todo/:id<int>/done", func(c *Context) {
+		c.String(http.StatusOK, c.Param("id"))
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/todo/abc/done", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/todo/-7/done", nil))
+	if w.Body.String() != "-7" {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+}
+
+func TestRegisterConstraint(t *testing.T) {
+	r := New()
+	r.RegisterConstraint("country", func(s string) bool { return s == "tw" || s == "jp" })
+	r.GET("/shop/:code<country>", func(c *Context) {
+		c.String(http.StatusOK, c.Param("code"))
+	}).Named("shop")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/shop/tw", nil))
+	if w.Body.String() != "tw" {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/shop/us", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status %d", w.Code)
+	}
+
+	if u, err := r.URL("shop", "jp"); err != nil || u != "/shop/jp" {
+		t.Fatalf("unexpected url %q %v", u, err)
+	}
+	if _, err := r.URL("shop", "us"); err == nil {
+		t.Fatal("expected an error for a value not matching the constraint")
+	}
+}
+
+func TestInvalidConstraint(t *testing.T) {
+	defer func() {
+		if err := recover(); err == nil || !strings.Contains(err.(string), "invalid constraint") {
+			t.Fatalf("unexpected panic %v", err)
+		}
+	}()
+	New().GET("/todo/:id<[0-9>", nil)
+}
+
+func TestOpenAPIConstraints(t *testing.T) {
+	r := New()
+	r.RegisterConstraint("country", func(s string) bool { return len(s) == 2 })
+	constraints := r.router.constraints
+	path, params := openAPIPath("/todo/:id<int>/:slug<[a-z]+>/:code<country>/:name<alpha>", constraints)
+	if path != "/todo/{id}/{slug}/{code}/{name}" {
+		t.Fatalf("unexpected path %q", path)
+	}
+	if params[0]["schema"].(H)["type"] != "integer" || params[1]["schema"].(H)["pattern"] != "^(?:[a-z]+)$" {
+		t.Fatalf("unexpected params %v", params)
+	}
+	if _, ok := params[2]["schema"].(H)["pattern"]; ok {
+		t.Fatalf("registered constraints have no pattern, got %v", params[2])
+	}
+	if params[3]["schema"].(H)["pattern"] != "^[A-Za-z]+$" {
+		t.Fatalf("unexpected params %v", params[3])
+	}
+}
@@ -1,9 +1,12 @@
 package goo
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
+	"net/url"
 )
 
 type H map[string]interface{}
@@ -16,13 +19,29 @@ type Context struct {
 	Path   string
 	Method string
 	Params map[string]string
+	// parsed query string, see Context.queryValues
+	queryCache url.Values
 	// response info
 	StatusCode int
+	// Errors are collected by Context.Error
+	Errors Errors
 	// middleware
 	handlers []HandlerFunc
 	index    int
 	// engine pointer
 	engine *Engine
+	// content types allowed by UploadLimit
+	uploadTypes []string
+	// SameSite attribute of the cookies set by SetCookie
+	sameSite http.SameSite
+	// nonce of the Content-Security-Policy, see Context.CSPNonce
+	cspNonce string
+	// tags of the response, see Cache.Invalidate
+	cacheTags []string
+	// run once the errors are rendered, see Logger
+	afterResponse []func()
+	// pattern of the matched route, empty for 404s and redirects
+	pattern string
 }
 
 func newContext(w http.ResponseWriter, req *http.Request) *Context {
@@ -30,11 +49,19 @@ func newContext(w http.ResponseWriter, req *http.Request) *Context {
 		Path:   req.URL.Path,
 		Method: req.Method,
 		Req:    req,
-		Writer: w,
+		Writer: &responseWriter{ResponseWriter: w},
 		index:  -1,
 	}
 }
 
+// CreateTestContext returns a context for calling a single HandlerFunc
+// in unit tests, set its Req and Params before calling the handler
+func CreateTestContext(w http.ResponseWriter) (*Context, *Engine) {
+	engine := New()
+	c := &Context{Writer: &responseWriter{ResponseWriter: w}, index: -1, engine: engine}
+	return c, engine
+}
+
 func (c *Context) Next() {
 	c.index++
 	s := len(c.handlers)
@@ -43,8 +70,22 @@ func (c *Context) Next() {
 	}
 }
 
+// abortIndex is past any handler chain, unlike the index of a chain
+// that ran to its end
+const abortIndex = math.MaxInt / 2
+
+// Abort stops the remaining handlers from being called
+func (c *Context) Abort() {
+	c.index = abortIndex
+}
+
+// IsAborted reports whether Abort or Fail was called
+func (c *Context) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
 func (c *Context) Fail(code int, err string) {
-	c.index = len(c.handlers)
+	c.index = abortIndex
 	c.JSON(code, H{"message": err})
 }
 
@@ -66,6 +107,28 @@ func (c *Context) Status(code int) {
 	c.Writer.WriteHeader(code)
 }
 
+// Redirect replies with a redirect to location
+func (c *Context) Redirect(code int, location string) {
+	c.StatusCode = code
+	http.Redirect(c.Writer, c.Req, location, code)
+}
+
+// Written reports whether the response was started, also by handlers
+// writing to c.Writer directly like WrapH, Proxy and ServeContent
+func (c *Context) Written() bool {
+	w := c.Writer
+	for {
+		if tracker, ok := w.(interface{ Written() bool }); ok {
+			return tracker.Written()
+		}
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return c.StatusCode != 0
+		}
+		w = unwrapper.Unwrap()
+	}
+}
+
 func (c *Context) SetHeader(key string, value string) {
 	c.Writer.Header().Set(key, value)
 }
@@ -90,12 +153,31 @@ func (c *Context) Data(code int, data []byte) {
 	c.Writer.Write(data)
 }
 
+type templateHookKey struct{}
+
+// WithTemplateHook returns a copy of ctx that makes Context.HTML call
+// hook with the name of every template it renders for requests using
+// it, so tests can check the templates of a request
+func WithTemplateHook(ctx context.Context, hook func(name string)) context.Context {
+	return context.WithValue(ctx, templateHookKey{}, hook)
+}
+
 // HTML template render
 // refer https://golang.org/pkg/html/template/
 func (c *Context) HTML(code int, name string, data interface{}) {
 	c.SetHeader("Content-Type", "text/html")
 	c.Status(code)
-	if err := c.engine.htmlTemplates.ExecuteTemplate(c.Writer, name, data); err != nil {
+	if c.engine.htmlRender == nil {
+		c.Fail(500, "html templates are not loaded")
+		return
+	}
+	if c.cspNonce != "" {
+		data = withNonce(data, c.cspNonce)
+	}
+	if hook, ok := c.Req.Context().Value(templateHookKey{}).(func(string)); ok {
+		hook(name)
+	}
+	if err := c.engine.htmlRender.Render(c.Writer, name, data); err != nil {
 		c.Fail(500, err.Error())
 	}
 }
@@ -0,0 +1,157 @@
+package goo
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrInvalidCookie is returned when a signed or encrypted cookie
+// was tampered with or was written with an unknown key
+var ErrInvalidCookie = errors.New("goo: invalid cookie")
+
+// SetCookieKeys sets the secrets of signed and encrypted cookies.
+// The first key writes new cookies, all of them are tried when reading
+// so keys can be rotated by prepending a new one
+func (engine *Engine) SetCookieKeys(keys ...[]byte) {
+	engine.cookieKeys = keys
+}
+
+// SetSameSite sets the SameSite attribute of the cookies set afterwards
+func (c *Context) SetSameSite(sameSite http.SameSite) {
+	c.sameSite = sameSite
+}
+
+// SetCookie adds a Set-Cookie header to the response
+func (c *Context) SetCookie(name, value string, maxAge int, path, domain string, secure, httpOnly bool) {
+	if path == "" {
+		path = "/"
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    url.QueryEscape(value),
+		MaxAge:   maxAge,
+		Path:     path,
+		Domain:   domain,
+		SameSite: c.sameSite,
+		Secure:   secure,
+		HttpOnly: httpOnly,
+	})
+}
+
+// Cookie returns the named cookie sent with the request, or http.ErrNoCookie
+func (c *Context) Cookie(name string) (string, error) {
+	cookie, err := c.Req.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return url.QueryUnescape(cookie.Value)
+}
+
+func cookieMAC(key []byte, name, value string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name + "=" + value))
+	return mac.Sum(nil)
+}
+
+// SetSignedCookie sets a cookie whose value can be read but not modified by the client
+func (c *Context) SetSignedCookie(name, value string, maxAge int, path, domain string, secure, httpOnly bool) error {
+	keys := c.engine.cookieKeys
+	if len(keys) == 0 {
+		return errors.New("goo: SetSignedCookie needs Engine.SetCookieKeys")
+	}
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(value))
+	signature := base64.RawURLEncoding.EncodeToString(cookieMAC(keys[0], name, encoded))
+	c.SetCookie(name, encoded+"."+signature, maxAge, path, domain, secure, httpOnly)
+	return nil
+}
+
+// SignedCookie returns the value of a cookie set by SetSignedCookie
+func (c *Context) SignedCookie(name string) (string, error) {
+	raw, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	encoded, signature, ok := strings.Cut(raw, ".")
+	if !ok {
+		return "", ErrInvalidCookie
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	for _, key := range c.engine.cookieKeys {
+		if hmac.Equal(mac, cookieMAC(key, name, encoded)) {
+			value, err := base64.RawURLEncoding.DecodeString(encoded)
+			if err != nil {
+				return "", ErrInvalidCookie
+			}
+			return string(value), nil
+		}
+	}
+	return "", ErrInvalidCookie
+}
+
+// cookieCipher derives an AES-256-GCM cipher from a cookie key,
+// so one key can both sign and encrypt
+func cookieCipher(key []byte) (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("goo cookie encryption"))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// SetEncryptedCookie sets a cookie whose value the client can neither read nor modify
+func (c *Context) SetEncryptedCookie(name, value string, maxAge int, path, domain string, secure, httpOnly bool) error {
+	keys := c.engine.cookieKeys
+	if len(keys) == 0 {
+		return errors.New("goo: SetEncryptedCookie needs Engine.SetCookieKeys")
+	}
+	aead, err := cookieCipher(keys[0])
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(name))
+	c.SetCookie(name, base64.RawURLEncoding.EncodeToString(sealed), maxAge, path, domain, secure, httpOnly)
+	return nil
+}
+
+// EncryptedCookie returns the value of a cookie set by SetEncryptedCookie
+func (c *Context) EncryptedCookie(name string) (string, error) {
+	raw, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	for _, key := range c.engine.cookieKeys {
+		aead, err := cookieCipher(key)
+		if err != nil {
+			return "", err
+		}
+		if len(sealed) < aead.NonceSize() {
+			return "", ErrInvalidCookie
+		}
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		if value, err := aead.Open(nil, nonce, ciphertext, []byte(name)); err == nil {
+			return string(value), nil
+		}
+	}
+	return "", ErrInvalidCookie
+}
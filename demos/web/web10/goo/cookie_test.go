@@ -0,0 +1,71 @@
+package goo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSignedCookies(t *testing.T) {
+	r := New()
+	r.SetCookieKeys([]byte("old secret"))
+	r.GET("/set", func(c *Context) {
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie("theme", "dark mode", 3600, "", "", false, true)
+		if err := c.SetSignedCookie("user", "gootutu", 3600, "", "", true, true); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.SetEncryptedCookie("token", "s3cret", 3600, "", "", true, true); err != nil {
+			t.Fatal(err)
+		}
+	})
+	r.GET("/get", func(c *Context) {
+		theme, _ := c.Cookie("theme")
+		user, err1 := c.SignedCookie("user")
+		token, err2 := c.EncryptedCookie("token")
+		c.JSON(http.StatusOK, H{"theme": theme, "user": user, "token": token, "ok": err1 == nil && err2 == nil})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/set", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 3 || cookies[0].SameSite != http.SameSiteLaxMode || !cookies[1].Secure {
+		t.Fatalf("unexpected cookies %v", cookies)
+	}
+
+	// rotate keys, cookies written with the old key are still readable
+	r.SetCookieKeys([]byte("new secret"), []byte("old secret"))
+	req := httptest.NewRequest("GET", "/get", nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	want := `{"ok":true,"theme":"dark mode","token":"s3cret","user":"gootutu"}` + "\n"
+	if w.Body.String() != want {
+		t.Fatalf("unexpected cookie values %q", w.Body.String())
+	}
+
+	// tampered values are rejected
+	req = httptest.NewRequest("GET", "/get", nil)
+	req.AddCookie(&http.Cookie{Name: "user", Value: "Z29vdHV0dQ.forged"})
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Body.String() != `{"ok":false,"theme":"","token":"","user":""}`+"\n" {
+		t.Fatalf("tampered cookie should be rejected, got %q", w.Body.String())
+	}
+}
+
+func TestCookieWithoutKeys(t *testing.T) {
+	r := New()
+	var errs []error
+	r.GET("/set", func(c *Context) {
+		errs = append(errs, c.SetSignedCookie("user", "gootutu", 3600, "", "", true, true))
+		errs = append(errs, c.SetEncryptedCookie("token", "s3cret", 3600, "", "", true, true))
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/set", nil))
+	if len(errs) != 2 || errs[0] == nil || errs[1] == nil || len(w.Result().Cookies()) != 0 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+}
@@ -0,0 +1,246 @@
+package goo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrorType tells whether the message of an error may be sent to the client
+type ErrorType uint8
+
+const (
+	// ErrorTypePrivate errors are only logged, the client gets the status text
+	ErrorTypePrivate ErrorType = iota
+	// ErrorTypePublic errors are sent to the client as the problem detail
+	ErrorTypePublic
+)
+
+// Error is an error collected by Context.Error
+type Error struct {
+	Err  error
+	Type ErrorType
+	// Status overrides the status the error is mapped to
+	Status int
+	// Meta is sent along with public errors
+	Meta interface{}
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) SetType(t ErrorType) *Error {
+	e.Type = t
+	return e
+}
+
+func (e *Error) SetStatus(code int) *Error {
+	e.Status = code
+	return e
+}
+
+func (e *Error) SetMeta(meta interface{}) *Error {
+	e.Meta = meta
+	return e
+}
+
+// Errors are the errors collected while handling a request
+type Errors []*Error
+
+// Last returns the last error or nil
+func (errs Errors) Last() *Error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs[len(errs)-1]
+}
+
+// ByType returns the errors of type t
+func (errs Errors) ByType(t ErrorType) Errors {
+	var filtered Errors
+	for _, e := range errs {
+		if e.Type == t {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+func (errs Errors) String() string {
+	var b strings.Builder
+	for i, e := range errs {
+		fmt.Fprintf(&b, "Error #%02d: %s", i+1, e.Err)
+		if e.Meta != nil {
+			fmt.Fprintf(&b, " meta: %v", e.Meta)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Error collects err for the ErrorHandler middleware and the logger.
+// Errors with a StatusCode below 500, like ParamError, are public,
+// other errors are private until their type is set
+func (c *Context) Error(err error) *Error {
+	if err == nil {
+		panic("goo: Context.Error called with a nil error")
+	}
+	var e *Error
+	if !errors.As(err, &e) {
+		e = &Error{Err: err, Type: ErrorTypePrivate}
+		var coded interface{ StatusCode() int }
+		if errors.As(err, &coded) && coded.StatusCode() < http.StatusInternalServerError {
+			e.Type = ErrorTypePublic
+		}
+	}
+	c.Errors = append(c.Errors, e)
+	return e
+}
+
+// Problem is an RFC 7807 problem details object, handlers can pass
+// one to Context.Error to control the whole response
+type Problem struct {
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Errors lists the other public errors of the request
+	Errors []string `json:"errors,omitempty"`
+	// Meta is the Meta of the public error
+	Meta interface{} `json:"meta,omitempty"`
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Title + ": " + p.Detail
+	}
+	return p.Title
+}
+
+func (p *Problem) StatusCode() int {
+	return p.Status
+}
+
+type errorStatus struct {
+	target error
+	code   int
+}
+
+// MapError sends code for errors matching target with errors.Is,
+// mapped errors take precedence over a StatusCode method
+func (engine *Engine) MapError(target error, code int) {
+	engine.errorStatuses = append(engine.errorStatuses, errorStatus{target, code})
+}
+
+func defaultErrorStatuses() []errorStatus {
+	return []errorStatus{
+		{http.ErrMissingFile, http.StatusBadRequest},
+		{ErrFileType, http.StatusUnsupportedMediaType},
+		{ErrInvalidCookie, http.StatusBadRequest},
+	}
+}
+
+// errorStatus maps e to the response status, 500 when nothing matches
+func (c *Context) errorStatus(e *Error) int {
+	if validStatus(e.Status) {
+		return e.Status
+	}
+	if c.engine != nil {
+		for i := len(c.engine.errorStatuses) - 1; i >= 0; i-- {
+			if s := c.engine.errorStatuses[i]; errors.Is(e.Err, s.target) {
+				return s.code
+			}
+		}
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(e.Err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	var coded interface{ StatusCode() int }
+	if errors.As(e.Err, &coded) && validStatus(coded.StatusCode()) {
+		return coded.StatusCode()
+	}
+	return http.StatusInternalServerError
+}
+
+// validStatus reports whether net/http can write code
+func validStatus(code int) bool {
+	return code >= 100 && code <= 599
+}
+
+// Problem builds the response for the collected errors, the last one
+// decides the status. Error handlers set with Engine.SetErrorHandler
+// can use it to render errors in other formats
+func (c *Context) Problem() *Problem {
+	last := c.Errors.Last()
+	var p *Problem
+	if errors.As(last.Err, &p) {
+		copied := *p
+		p = &copied
+		if !validStatus(p.Status) {
+			p.Status = c.errorStatus(last)
+		}
+		if p.Title == "" {
+			p.Title = http.StatusText(p.Status)
+		}
+	} else {
+		status := c.errorStatus(last)
+		p = &Problem{Title: http.StatusText(status), Status: status}
+		if last.Type == ErrorTypePublic {
+			p.Detail = last.Err.Error()
+			p.Meta = last.Meta
+		}
+	}
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Instance == "" && c.Req != nil {
+		p.Instance = c.Req.URL.Path
+	}
+	for _, e := range c.Errors[:len(c.Errors)-1] {
+		if e.Type == ErrorTypePublic {
+			p.Errors = append(p.Errors, e.Err.Error())
+		}
+	}
+	return p
+}
+
+// renderErrors writes the collected errors with the engine's error
+// handler or as problem+json, unless a response was already written
+func (c *Context) renderErrors() {
+	if len(c.Errors) == 0 || c.Written() {
+		return
+	}
+	if c.engine != nil && c.engine.errorHandler != nil {
+		c.engine.errorHandler(c)
+		return
+	}
+	p := c.Problem()
+	data, err := json.Marshal(p)
+	if err != nil {
+		c.Fail(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.SetHeader("Content-Type", "application/problem+json")
+	c.Status(p.Status)
+	c.Writer.Write(data)
+}
+
+// ErrorHandler renders the errors collected by the handlers behind it
+// as RFC 7807 problem+json responses, or with Engine.SetErrorHandler,
+// so the middlewares in front of it see the status. Private messages
+// are left out of the response and logged by Logger
+func ErrorHandler() HandlerFunc {
+	return func(c *Context) {
+		c.Next()
+		c.renderErrors()
+	}
+}
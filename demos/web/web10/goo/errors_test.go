@@ -0,0 +1,213 @@
+package goo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+var errNotFound = errors.New("todo not found")
+
+func serveProblem(t *testing.T, r *Engine, path string) (*httptest.ResponseRecorder, Problem) {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	var p Problem
+	if w.Header().Get("Content-Type") == "application/problem+json" {
+		if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+			t.Fatalf("unexpected body %q", w.Body.String())
+		}
+	}
+	return w, p
+}
+
+func TestErrorHandler(t *testing.T) {
+	r := New()
+	r.Use(ErrorHandler())
+	r.MapError(errNotFound, http.StatusNotFound)
+	r.GET("/private", func(c *Context) {
+		c.Error(errors.New("db password is hunter2"))
+	})
+	r.GET("/mapped", func(c *Context) {
+		c.Error(errNotFound).SetType(ErrorTypePublic)
+	})
+	r.GET("/param/:id", func(c *Context) {
+		if _, err := c.ParamInt("id"); err != nil {
+			c.Error(err)
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+	r.GET("/problem", func(c *Context) {
+		c.Error(errors.New("first")).SetType(ErrorTypePublic)
+		c.Error(&Problem{Type: "https://example.com/quota", Title: "Quota exceeded", Status: http.StatusTooManyRequests})
+	})
+	r.GET("/written", func(c *Context) {
+		c.Error(errors.New("too late"))
+		c.String(http.StatusOK, "ok")
+	})
+
+	w, p := serveProblem(t, r, "/private")
+	if w.Code != http.StatusInternalServerError || p.Detail != "" || strings.Contains(w.Body.String(), "hunter2") {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	if p.Type != "about:blank" || p.Title != "Internal Server Error" || p.Instance != "/private" {
+		t.Fatalf("unexpected problem %+v", p)
+	}
+
+	w, p = serveProblem(t, r, "/mapped")
+	if w.Code != http.StatusNotFound || p.Detail != "todo not found" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+
+	w, p = serveProblem(t, r, "/param/abc")
+	if w.Code != http.StatusBadRequest || !strings.Contains(p.Detail, `"abc" is not a valid int`) {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+
+	w, p = serveProblem(t, r, "/problem")
+	if w.Code != http.StatusTooManyRequests || p.Type != "https://example.com/quota" || len(p.Errors) != 1 || p.Errors[0] != "first" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+
+	w, _ = serveProblem(t, r, "/written")
+	if w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestLoggerLogsErrors(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	r := New()
+	r.Use(Logger(), ErrorHandler())
+	r.GET("/private", func(c *Context) {
+		c.Error(errors.New("db password is hunter2"))
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/private", nil))
+	if !strings.Contains(buf.String(), "Error #01: db password is hunter2") {
+		t.Fatalf("unexpected log %q", buf.String())
+	}
+}
+
+func TestLoggerLogsErrorStatus(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	r := New()
+	r.Use(Logger())
+	r.GET("/missing", func(c *Context) {
+		c.Error(errors.New("no such student")).SetStatus(http.StatusNotFound)
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+	if w.Code != http.StatusNotFound || !strings.Contains(buf.String(), "[404] /missing") {
+		t.Fatalf("unexpected log %q for %d", buf.String(), w.Code)
+	}
+}
+
+func TestErrorsAfterDirectWrite(t *testing.T) {
+	r := New()
+	r.GET("/raw", func(c *Context) {
+		c.Writer.Write([]byte("raw"))
+		c.Error(errors.New("too late"))
+	})
+	r.GET("/content", func(c *Context) {
+		http.ServeContent(c.Writer, c.Req, "a.txt", time.Time{}, strings.NewReader("content"))
+		c.Error(errors.New("too late"))
+	})
+	for path, body := range map[string]string{"/raw": "raw", "/content": "content"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK || w.Body.String() != body {
+			t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestErrHandlerFunc(t *testing.T) {
+	r := New()
+	var seen error
+	r.Use(func(c *Context) {
+		c.Next()
+		if e := c.Errors.Last(); e != nil {
+			seen = e.Err
+		}
+	})
+	r.GETErr("/todo/:id", func(c *Context) error {
+		id, err := c.ParamInt("id")
+		if err != nil {
+			return err
+		}
+		c.String(http.StatusOK, "todo %d", id)
+		return nil
+	})
+
+	w, p := serveProblem(t, r, "/todo/abc")
+	if w.Code != http.StatusBadRequest || p.Status != http.StatusBadRequest {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	var paramErr *ParamError
+	if !errors.As(seen, &paramErr) || paramErr.Key != "id" {
+		t.Fatalf("unexpected error seen by middleware %v", seen)
+	}
+
+	w, _ = serveProblem(t, r, "/todo/1")
+	if w.Code != http.StatusOK || w.Body.String() != "todo 1" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+
+	if routes := r.Routes(); !strings.HasSuffix(routes[0].Handler, "TestErrHandlerFunc.func2") {
+		t.Fatalf("unexpected handler name %q", routes[0].Handler)
+	}
+}
+
+func TestSetErrorHandler(t *testing.T) {
+	r := New()
+	r.SetErrorHandler(func(c *Context) {
+		p := c.Problem()
+		c.String(p.Status, "oops: %s", p.Title)
+	})
+	r.POSTErr("/fail", func(c *Context) error {
+		return errors.New("boom")
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/fail", nil))
+	if w.Code != http.StatusInternalServerError || w.Body.String() != "oops: Internal Server Error" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+}
+
+type zeroStatusError struct{}
+
+func (zeroStatusError) Error() string   { return "zero" }
+func (zeroStatusError) StatusCode() int { return 0 }
+
+func TestErrorInvalidStatus(t *testing.T) {
+	r := New()
+	r.GET("/problem", func(c *Context) {
+		c.Error(&Problem{Title: "bad"})
+	})
+	r.GET("/zero", func(c *Context) {
+		c.Error(zeroStatusError{})
+	})
+	r.GET("/status", func(c *Context) {
+		c.Error(errors.New("boom")).SetStatus(1000)
+	})
+	for _, path := range []string{"/problem", "/zero", "/status"} {
+		w, p := serveProblem(t, r, path)
+		if w.Code != http.StatusInternalServerError || p.Status != http.StatusInternalServerError {
+			t.Fatalf("unexpected response for %s: %d %q", path, w.Code, w.Body.String())
+		}
+	}
+}
@@ -2,19 +2,32 @@ package goo
 
 import (
 	"html/template"
+	"io/fs"
 	"log"
 	"net/http"
-	"path"
 	"strings"
 )
 
 // HandlerFunc defines the request handler used by goo
 type HandlerFunc func(*Context)
 
+// ErrHandlerFunc is a handler returning its error instead of writing it,
+// the error is collected with Context.Error and rendered by the engine
+type ErrHandlerFunc func(*Context) error
+
+func (h ErrHandlerFunc) handlerFunc() HandlerFunc {
+	return func(c *Context) {
+		if err := h(c); err != nil {
+			c.Error(err)
+		}
+	}
+}
+
 // Engine implement the interface of ServeHTTP
 type (
 	RouterGroup struct {
 		prefix      string
+		host        string        // host pattern, "" is the default host
 		middlewares []HandlerFunc // support middleware
 		parent      *RouterGroup  // support nesting
 		engine      *Engine       // all groups share a Engine instance
@@ -22,16 +35,47 @@ type (
 
 	Engine struct {
 		*RouterGroup
-		router        *router
-		groups        []*RouterGroup     // store all groups
-		htmlTemplates *template.Template // for html render
-		funcMap       template.FuncMap   // for html render
+		// MaxMultipartMemory is the memory limit of Context.MultipartForm,
+		// the rest of the files is stored on disk
+		MaxMultipartMemory int64
+		// RedirectTrailingSlash redirects /hello/ to /hello and the other
+		// way round when the route was registered with a trailing slash,
+		// otherwise both are served
+		RedirectTrailingSlash bool
+		// RedirectFixedPath redirects requests matching no route to the
+		// cleaned path with the case of the route, /../HELLO to /hello
+		RedirectFixedPath bool
+		// RemoveExtraSlash redirects paths like //hello//world to /hello/world
+		RemoveExtraSlash bool
+
+		router     *router
+		groups     []*RouterGroup   // store all groups
+		htmlRender HTMLRender       // for html render
+		funcMap    template.FuncMap // for html render
+		htmlDebug  bool             // reload changed templates on render
+		renderers  []Renderer       // formats offered by Context.Negotiate
+		// written before json arrays by Context.SecureJSON
+		secureJSONPrefix string
+		webSocketConfig  WebSocketConfig // for Context.Upgrade
+		cookieKeys       [][]byte        // for signed and encrypted cookies
+		routes           []*Route        // in registration order
+		namedRoutes      map[string]*Route
+		shadowWarned     map[*Route]bool
+		hosts            []*hostRouter // virtual hosts, see Engine.Host
+		errorStatuses    []errorStatus // see Engine.MapError
+		errorHandler     HandlerFunc   // see Engine.SetErrorHandler
 	}
 )
 
 // New is the constructor of goo.Engine
 func New() *Engine {
-	engine := &Engine{router: newRouter()}
+	engine := &Engine{
+		MaxMultipartMemory: defaultMultipartMemory,
+		router:             newRouter(),
+		renderers:          defaultRenderers(),
+		secureJSONPrefix:   "while(1);",
+		errorStatuses:      defaultErrorStatuses(),
+	}
 	engine.RouterGroup = &RouterGroup{engine: engine}
 	engine.groups = []*RouterGroup{engine.RouterGroup}
 	return engine
@@ -50,6 +94,7 @@ func (group *RouterGroup) Group(prefix string) *RouterGroup {
 	engine := group.engine
 	newGroup := &RouterGroup{
 		prefix: group.prefix + prefix,
+		host:   group.host,
 		parent: group,
 		engine: engine,
 	}
@@ -62,44 +107,54 @@ func (group *RouterGroup) Use(middlewares ...HandlerFunc) {
 	group.middlewares = append(group.middlewares, middlewares...)
 }
 
-func (group *RouterGroup) addRoute(method string, comp string, handler HandlerFunc) {
+func (group *RouterGroup) addRoute(method string, comp string, handler HandlerFunc) *Route {
 	pattern := group.prefix + comp
-	log.Printf("Route %4s - %s", method, pattern)
-	group.engine.router.addRoute(method, pattern, handler)
+	log.Printf("Route %4s - %s%s", method, group.host, pattern)
+	engine := group.engine
+	engine.routerFor(group.host).addRoute(method, pattern, handler)
+	route := &Route{Method: method, Pattern: pattern, Host: group.host, handler: handler, engine: engine}
+	engine.routes = append(engine.routes, route)
+	engine.warnShadowedRoutes(method, group.host)
+	return route
+}
+
+// Handle registers handler for any request method
+func (group *RouterGroup) Handle(method string, pattern string, handler HandlerFunc) *Route {
+	return group.addRoute(method, pattern, handler)
 }
 
 // GET defines the method to add GET request
-func (group *RouterGroup) GET(pattern string, handler HandlerFunc) {
-	group.addRoute("GET", pattern, handler)
+func (group *RouterGroup) GET(pattern string, handler HandlerFunc) *Route {
+	return group.addRoute("GET", pattern, handler)
 }
 
 // POST defines the method to add POST request
-func (group *RouterGroup) POST(pattern string, handler HandlerFunc) {
-	group.addRoute("POST", pattern, handler)
+func (group *RouterGroup) POST(pattern string, handler HandlerFunc) *Route {
+	return group.addRoute("POST", pattern, handler)
 }
 
-// create static handler
-func (group *RouterGroup) createStaticHandler(relativePath string, fs http.FileSystem) HandlerFunc {
-	absolutePath := path.Join(group.prefix, relativePath)
-	fileServer := http.StripPrefix(absolutePath, http.FileServer(fs))
-	return func(c *Context) {
-		file := c.Param("filepath")
-		// Check if file exists and/or if we have permission to access it
-		if _, err := fs.Open(file); err != nil {
-			c.Status(http.StatusNotFound)
-			return
-		}
+// HandleErr registers an ErrHandlerFunc for any request method
+func (group *RouterGroup) HandleErr(method string, pattern string, handler ErrHandlerFunc) *Route {
+	route := group.addRoute(method, pattern, handler.handlerFunc())
+	route.handler = handler
+	return route
+}
 
-		fileServer.ServeHTTP(c.Writer, c.Req)
-	}
+// GETErr registers an ErrHandlerFunc for GET requests
+func (group *RouterGroup) GETErr(pattern string, handler ErrHandlerFunc) *Route {
+	return group.HandleErr("GET", pattern, handler)
 }
 
-// serve static files
-func (group *RouterGroup) Static(relativePath string, root string) {
-	handler := group.createStaticHandler(relativePath, http.Dir(root))
-	urlPattern := path.Join(relativePath, "/*filepath")
-	// Register GET handlers
-	group.GET(urlPattern, handler)
+// POSTErr registers an ErrHandlerFunc for POST requests
+func (group *RouterGroup) POSTErr(pattern string, handler ErrHandlerFunc) *Route {
+	return group.HandleErr("POST", pattern, handler)
+}
+
+// SetErrorHandler replaces the problem+json response written for the
+// errors collected while handling a request. It runs after the handlers
+// when nothing was written yet, and in ErrorHandler when that is used
+func (engine *Engine) SetErrorHandler(handler HandlerFunc) {
+	engine.errorHandler = handler
 }
 
 // for custom render function
@@ -107,24 +162,89 @@ func (engine *Engine) SetFuncMap(funcMap template.FuncMap) {
 	engine.funcMap = funcMap
 }
 
-func (engine *Engine) LoadHTMLGlob(pattern string) {
-	engine.htmlTemplates = template.Must(template.New("").Funcs(engine.funcMap).ParseGlob(pattern))
+// SetHTMLDebug makes the loaded templates re-parse themselves whenever
+// a template file changes, so editing them doesn't need a restart
+func (engine *Engine) SetHTMLDebug(debug bool) {
+	engine.htmlDebug = debug
+	if t, ok := engine.htmlRender.(*HTMLTemplates); ok {
+		t.Debug = debug
+	}
+}
+
+// SetHTMLRender replaces the template engine used by Context.HTML
+func (engine *Engine) SetHTMLRender(render HTMLRender) {
+	engine.htmlRender = render
+}
+
+// HTMLRender returns the template engine used by Context.HTML
+func (engine *Engine) HTMLRender() HTMLRender {
+	return engine.htmlRender
+}
+
+// LoadHTMLGlob parses every template matched by the patterns into one set
+func (engine *Engine) LoadHTMLGlob(patterns ...string) {
+	engine.loadHTML(&HTMLTemplates{Pages: patterns})
+}
+
+// LoadHTMLFS is like LoadHTMLGlob but reads the templates from fsys
+func (engine *Engine) LoadHTMLFS(fsys fs.FS, patterns ...string) {
+	engine.loadHTML(&HTMLTemplates{Pages: patterns, FS: fsys})
+}
+
+// LoadHTMLLayout parses each page matched by pages together with
+// the layouts and partials matched by layouts
+func (engine *Engine) LoadHTMLLayout(layouts []string, pages ...string) {
+	engine.loadHTML(&HTMLTemplates{Pages: pages, Layouts: layouts})
+}
+
+func (engine *Engine) loadHTML(t *HTMLTemplates) {
+	t.FuncMap = template.FuncMap{"url": engine.URL}
+	for name, fn := range engine.funcMap {
+		t.FuncMap[name] = fn
+	}
+	t.Debug = engine.htmlDebug
+	if err := t.Load(); err != nil {
+		panic(err)
+	}
+	engine.htmlRender = t
 }
 
 // Run defines the method to start a http server
 func (engine *Engine) Run(addr string) (err error) {
+	if err = engine.CheckURLs(); err != nil {
+		return err
+	}
 	return http.ListenAndServe(addr, engine)
 }
 
+// RunH2C serves HTTP/1.1 and cleartext HTTP/2, see Engine.H2CServer
+func (engine *Engine) RunH2C(addr string) error {
+	if err := engine.CheckURLs(); err != nil {
+		return err
+	}
+	server, err := engine.H2CServer(addr)
+	if err != nil {
+		return err
+	}
+	return server.ListenAndServe()
+}
+
 func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	router, host, hostParams := engine.matchHost(req)
 	var middlewares []HandlerFunc
 	for _, group := range engine.groups {
-		if strings.HasPrefix(req.URL.Path, group.prefix) {
+		if (group.host == "" || group.host == host) && strings.HasPrefix(req.URL.Path, group.prefix) {
 			middlewares = append(middlewares, group.middlewares...)
 		}
 	}
 	c := newContext(w, req)
 	c.handlers = middlewares
 	c.engine = engine
-	engine.router.handle(c)
+	c.Params = hostParams
+	router.handle(c)
+	// errors nothing rendered, e.g. without the ErrorHandler middleware
+	c.renderErrors()
+	for _, f := range c.afterResponse {
+		f()
+	}
 }
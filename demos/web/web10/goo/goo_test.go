@@ -0,0 +1,39 @@
+package goo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"templates/hello.tmpl": {Data: []byte("hello {{.}}")},
+		"static/file1.txt":     {Data: []byte("file1")},
+	}
+	r := New()
+	r.LoadHTMLFS(fsys, "templates/*")
+	r.StaticFS("/assets", fsys)
+	r.GET("/", func(c *Context) {
+		c.HTML(http.StatusOK, "hello.tmpl", "goo")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "hello goo" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/assets/static/file1.txt", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "file1" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/assets/static/missing.txt", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("missing file should be 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,267 @@
+// Package gootest runs requests against a goo.Engine in-process
+// and checks the responses, e.g.
+//
+//	gootest.New(t, r).POST("/todo").JSON(todo).Do().
+//		Status(http.StatusCreated).
+//		JSONPath("title", "learn goo")
+package gootest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"goo"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Client sends requests to an engine and keeps the cookies it sets
+type Client struct {
+	t       testing.TB
+	engine  *goo.Engine
+	cookies map[string]*http.Cookie
+}
+
+func New(t testing.TB, engine *goo.Engine) *Client {
+	return &Client{t: t, engine: engine, cookies: make(map[string]*http.Cookie)}
+}
+
+// Request is built fluently and sent with Do
+type Request struct {
+	client *Client
+	req    *http.Request
+	err    error
+}
+
+func (c *Client) Request(method, path string) *Request {
+	return &Request{client: c, req: httptest.NewRequest(method, path, nil)}
+}
+
+func (c *Client) GET(path string) *Request {
+	return c.Request(http.MethodGet, path)
+}
+
+func (c *Client) POST(path string) *Request {
+	return c.Request(http.MethodPost, path)
+}
+
+func (c *Client) PUT(path string) *Request {
+	return c.Request(http.MethodPut, path)
+}
+
+func (c *Client) DELETE(path string) *Request {
+	return c.Request(http.MethodDelete, path)
+}
+
+func (r *Request) Header(key, value string) *Request {
+	r.req.Header.Set(key, value)
+	return r
+}
+
+func (r *Request) Cookie(name, value string) *Request {
+	r.req.AddCookie(&http.Cookie{Name: name, Value: value})
+	return r
+}
+
+func (r *Request) Query(key, value string) *Request {
+	q := r.req.URL.Query()
+	q.Add(key, value)
+	r.req.URL.RawQuery = q.Encode()
+	r.req.RequestURI = r.req.URL.RequestURI()
+	return r
+}
+
+func (r *Request) setBody(contentType string, body []byte) {
+	r.req.Body = io.NopCloser(bytes.NewReader(body))
+	r.req.ContentLength = int64(len(body))
+	r.req.Header.Set("Content-Type", contentType)
+}
+
+// JSON sends obj encoded as json
+func (r *Request) JSON(obj interface{}) *Request {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	r.setBody("application/json", body)
+	return r
+}
+
+// Form sends values url-encoded
+func (r *Request) Form(values url.Values) *Request {
+	r.setBody("application/x-www-form-urlencoded", []byte(values.Encode()))
+	return r
+}
+
+// Body sends body as is
+func (r *Request) Body(contentType string, body []byte) *Request {
+	r.setBody(contentType, body)
+	return r
+}
+
+// Do serves the request with the engine
+func (r *Request) Do() *Response {
+	c := r.client
+	c.t.Helper()
+	if r.err != nil {
+		c.t.Fatalf("gootest: %v", r.err)
+	}
+	for _, cookie := range c.cookies {
+		if _, err := r.req.Cookie(cookie.Name); err == http.ErrNoCookie {
+			r.req.AddCookie(cookie)
+		}
+	}
+
+	// recorded per request so parallel tests can share the engine
+	var templates []string
+	req := r.req.WithContext(goo.WithTemplateHook(r.req.Context(), func(name string) {
+		templates = append(templates, name)
+	}))
+
+	w := httptest.NewRecorder()
+	c.engine.ServeHTTP(w, req)
+
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.MaxAge < 0 {
+			delete(c.cookies, cookie.Name)
+		} else {
+			c.cookies[cookie.Name] = cookie
+		}
+	}
+	return &Response{ResponseRecorder: w, Templates: templates, t: c.t}
+}
+
+// Response wraps the recorded response with assertions,
+// each of them reports a test error and returns the response
+type Response struct {
+	*httptest.ResponseRecorder
+	// Templates are the html templates rendered while serving
+	Templates []string
+	t         testing.TB
+	json      interface{}
+}
+
+func (r *Response) Status(code int) *Response {
+	r.t.Helper()
+	if r.Code != code {
+		r.t.Errorf("status = %d, want %d, body: %s", r.Code, code, r.Body.String())
+	}
+	return r
+}
+
+func (r *Response) Header(key, want string) *Response {
+	r.t.Helper()
+	if got := r.Result().Header.Get(key); got != want {
+		r.t.Errorf("header %s = %q, want %q", key, got, want)
+	}
+	return r
+}
+
+func (r *Response) BodyContains(s string) *Response {
+	r.t.Helper()
+	if !strings.Contains(r.Body.String(), s) {
+		r.t.Errorf("body %q doesn't contain %q", r.Body.String(), s)
+	}
+	return r
+}
+
+// Template checks that the named template was rendered
+func (r *Response) Template(name string) *Response {
+	r.t.Helper()
+	for _, rendered := range r.Templates {
+		if rendered == name {
+			return r
+		}
+	}
+	r.t.Errorf("template %q wasn't rendered, got %q", name, r.Templates)
+	return r
+}
+
+// DecodeJSON decodes the body into v
+func (r *Response) DecodeJSON(v interface{}) *Response {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Body.Bytes(), v); err != nil {
+		r.t.Errorf("decode json body: %v", err)
+	}
+	return r
+}
+
+// JSONPath checks the value at a dot separated path of the json body,
+// array elements are addressed by index, e.g. "todos.0.title".
+// want is compared after a json round trip so 1 equals 1.0
+func (r *Response) JSONPath(path string, want interface{}) *Response {
+	r.t.Helper()
+	got, err := r.lookup(path)
+	if err != nil {
+		r.t.Errorf("json path %s: %v", path, err)
+		return r
+	}
+	normalized, err := roundTrip(want)
+	if err != nil {
+		r.t.Errorf("json path %s: %v", path, err)
+		return r
+	}
+	if !reflect.DeepEqual(got, normalized) {
+		r.t.Errorf("json path %s = %v, want %v", path, got, normalized)
+	}
+	return r
+}
+
+func roundTrip(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	err = json.Unmarshal(data, &out)
+	return out, err
+}
+
+func (r *Response) lookup(path string) (interface{}, error) {
+	if r.json == nil {
+		if err := json.Unmarshal(r.Body.Bytes(), &r.json); err != nil {
+			return nil, err
+		}
+	}
+	value := r.json
+	if path == "" {
+		return value, nil
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if value, ok = v[key]; !ok {
+				return nil, fmt.Errorf("no key %q", key)
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("no index %q", key)
+			}
+			value = v[i]
+		default:
+			return nil, fmt.Errorf("%q is not an object or array", key)
+		}
+	}
+	return value, nil
+}
+
+// CreateTestContext returns a context for req to unit test a single
+// HandlerFunc, along with the recorder capturing its response
+func CreateTestContext(req *http.Request) (*goo.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := goo.CreateTestContext(w)
+	c.Req = req
+	c.Method = req.Method
+	c.Path = req.URL.Path
+	c.Params = make(map[string]string)
+	return c, w
+}
@@ -0,0 +1,72 @@
+package gootest
+
+import (
+	"goo"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestClient(t *testing.T) {
+	r := goo.New()
+	r.LoadHTMLFS(fstest.MapFS{"todo.tmpl": {Data: []byte("{{.}}")}}, "*.tmpl")
+	r.POST("/login", func(c *goo.Context) {
+		c.SetCookie("user", c.PostForm("username"), 0, "", "", false, true)
+		c.Status(http.StatusNoContent)
+	})
+	r.GET("/todo", func(c *goo.Context) {
+		user, _ := c.Cookie("user")
+		c.HTML(http.StatusOK, "todo.tmpl", user)
+	})
+	r.POST("/todo", func(c *goo.Context) {
+		c.JSON(http.StatusCreated, goo.H{"todos": []goo.H{{"id": 1, "title": c.Req.Header.Get("X-Title")}}})
+	})
+
+	client := New(t, r)
+	client.POST("/login").Form(map[string][]string{"username": {"gootutu"}}).Do().
+		Status(http.StatusNoContent)
+	client.GET("/todo").Do().
+		Status(http.StatusOK).
+		Template("todo.tmpl").
+		BodyContains("gootutu")
+	client.POST("/todo").Header("X-Title", "learn goo").JSON(goo.H{}).Do().
+		Status(http.StatusCreated).
+		Header("Content-Type", "application/json").
+		JSONPath("todos.0.id", 1).
+		JSONPath("todos.0.title", "learn goo")
+}
+
+func TestCreateTestContext(t *testing.T) {
+	c, w := CreateTestContext(httptest.NewRequest("GET", "/hello/goo", nil))
+	c.Params["name"] = "goo"
+	func(c *goo.Context) {
+		c.String(http.StatusOK, "hello %s", c.Param("name"))
+	}(c)
+	if w.Body.String() != "hello goo" {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+}
+
+func TestClientParallelTemplates(t *testing.T) {
+	r := goo.New()
+	r.LoadHTMLFS(fstest.MapFS{
+		"a.tmpl": {Data: []byte("a")},
+		"b.tmpl": {Data: []byte("b")},
+	}, "*.tmpl")
+	r.GET("/:page", func(c *goo.Context) {
+		c.HTML(http.StatusOK, c.Param("page")+".tmpl", nil)
+	})
+	for _, page := range []string{"a", "b", "a", "b"} {
+		page := page
+		t.Run(page, func(t *testing.T) {
+			t.Parallel()
+			for i := 0; i < 20; i++ {
+				res := New(t, r).GET("/" + page).Do().Status(http.StatusOK)
+				if len(res.Templates) != 1 || res.Templates[0] != page+".tmpl" {
+					t.Fatalf("unexpected templates %q", res.Templates)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+//go:build go1.24
+
+package goo
+
+import "net/http"
+
+// H2CServer returns a server for addr speaking HTTP/1.1 and HTTP/2
+// without tls, for running behind a proxy that talks h2c with prior
+// knowledge. The Upgrade: h2c handshake isn't supported
+func (engine *Engine) H2CServer(addr string) (*http.Server, error) {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return &http.Server{Addr: addr, Handler: engine, Protocols: protocols}, nil
+}
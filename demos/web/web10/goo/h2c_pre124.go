@@ -0,0 +1,15 @@
+//go:build !go1.24
+
+package goo
+
+import (
+	"errors"
+	"net/http"
+)
+
+var errH2CUnsupported = errors.New("goo: h2c needs a binary built with Go 1.24 or later")
+
+// H2CServer needs Go 1.24, whose net/http speaks HTTP/2 without tls
+func (engine *Engine) H2CServer(addr string) (*http.Server, error) {
+	return nil, errH2CUnsupported
+}
@@ -0,0 +1,99 @@
+package goo
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// hostRouter holds the routes of one virtual host
+type hostRouter struct {
+	pattern string
+	labels  []string
+	router  *router
+}
+
+// Host returns a group whose routes only match requests for host.
+// A label starting with ':' matches any subdomain and is available
+// through Context.Param, e.g. ":tenant.example.com". Requests whose
+// path has no route on their host fall back to the default host. It
+// panics on empty labels so mistyped hosts are found at startup
+func (engine *Engine) Host(pattern string) *RouterGroup {
+	// example.com. is the fully qualified example.com
+	pattern = strings.TrimSuffix(strings.ToLower(pattern), ".")
+	for _, label := range strings.Split(pattern, ".") {
+		if label == "" || label == ":" {
+			panic("goo: host " + pattern + " has an empty label")
+		}
+	}
+	engine.routerFor(pattern)
+	group := &RouterGroup{host: pattern, parent: engine.RouterGroup, engine: engine}
+	engine.groups = append(engine.groups, group)
+	return group
+}
+
+func (engine *Engine) routerFor(host string) *router {
+	if host == "" {
+		return engine.router
+	}
+	for _, h := range engine.hosts {
+		if h.pattern == host {
+			return h.router
+		}
+	}
+	h := &hostRouter{pattern: host, labels: strings.Split(host, "."), router: newRouter()}
+	h.router.constraints = engine.router.constraints
+	engine.hosts = append(engine.hosts, h)
+	return h.router
+}
+
+// match returns the subdomain params when host matches the pattern
+func (h *hostRouter) match(host string) (map[string]string, bool) {
+	labels := strings.Split(host, ".")
+	if len(labels) != len(h.labels) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, label := range h.labels {
+		if label[0] == ':' && labels[i] != "" {
+			params[label[1:]] = labels[i]
+		} else if label != labels[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// matchHost picks the router serving req, exact hosts win over
+// wildcard ones, and the default router when no host has the route
+func (engine *Engine) matchHost(req *http.Request) (*router, string, map[string]string) {
+	if len(engine.hosts) == 0 {
+		return engine.router, "", nil
+	}
+	host := strings.ToLower(req.Host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(host, ".")
+
+	type candidate struct {
+		h      *hostRouter
+		params map[string]string
+	}
+	var candidates []candidate
+	for _, h := range engine.hosts {
+		if params, ok := h.match(host); ok {
+			candidates = append(candidates, candidate{h, params})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return len(candidates[i].params) < len(candidates[j].params)
+	})
+	for _, c := range candidates {
+		if n, _ := c.h.router.getRoute(req.Method, req.URL.Path); n != nil {
+			return c.h.router, c.h.pattern, c.params
+		}
+	}
+	return engine.router, "", nil
+}
@@ -0,0 +1,64 @@
+package goo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHost(t *testing.T) {
+	r := New()
+	r.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "default")
+	})
+	r.GET("/about", func(c *Context) {
+		c.String(http.StatusOK, "about")
+	})
+
+	api := r.Host("api.example.com.")
+	api.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "api")
+	})
+	tenants := r.Host(":tenant.example.com")
+	tenants.Use(func(c *Context) {
+		c.SetHeader("X-Tenant", c.Param("tenant"))
+	})
+	tenants.Group("/todo").GET("/:id", func(c *Context) {
+		c.String(http.StatusOK, "%s %s", c.Param("tenant"), c.Param("id"))
+	})
+
+	tests := []struct {
+		host, path, body, tenant string
+	}{
+		{"example.com", "/", "default", ""},
+		{"API.example.com:8080", "/", "api", ""},
+		{"acme.example.com", "/todo/7", "acme 7", "acme"},
+		{"acme.example.com", "/about", "about", ""},
+		{"api.example.com", "/todo/7", "api 7", "api"},
+		{"api.example.com.", "/", "api", ""},
+		{"acme.example.com.:8080", "/todo/7", "acme 7", "acme"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", test.path, nil)
+		req.Host = test.host
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Body.String() != test.body || w.Header().Get("X-Tenant") != test.tenant {
+			t.Errorf("%s%s = %q (tenant %q), want %q (tenant %q)",
+				test.host, test.path, w.Body.String(), w.Header().Get("X-Tenant"), test.body, test.tenant)
+		}
+	}
+}
+
+func TestHostEmptyLabel(t *testing.T) {
+	for _, pattern := range []string{"a..b", ".example.com", "example.com..", ":.example.com"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("host %q should panic", pattern)
+				}
+			}()
+			New().Host(pattern)
+		}()
+	}
+}
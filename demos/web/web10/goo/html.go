@@ -0,0 +1,273 @@
+package goo
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"text/template/parse"
+	"time"
+)
+
+// HTMLRender is used by Context.HTML to execute a named template
+type HTMLRender interface {
+	Render(w io.Writer, name string, data interface{}) error
+}
+
+// HTMLTemplates renders templates parsed from glob patterns.
+// Without Layouts every matched file is parsed into one shared set,
+// which is how LoadHTMLGlob always worked. With Layouts each page is
+// parsed into its own set together with the layout and partial files,
+// so pages can redefine the same blocks, e.g.
+//
+//	{{template "base.tmpl" .}}
+//	{{define "content"}}...{{end}}
+type HTMLTemplates struct {
+	Pages   []string // glob patterns of page templates, named after their file
+	Layouts []string // glob patterns of layouts and partials shared by every page
+	FuncMap template.FuncMap
+	// FS is read instead of the local disk when set, e.g. an embed.FS
+	FS fs.FS
+	// Debug re-parses the templates on the next render whenever
+	// a matched file is added, removed or modified
+	Debug bool
+
+	mu       sync.RWMutex
+	sets     map[string]*template.Template // page name -> template set
+	shared   *template.Template            // used when there are no layouts
+	modTimes map[string]time.Time
+}
+
+// Load parses all templates, it is called once by LoadHTMLGlob
+func (t *HTMLTemplates) Load() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.load()
+}
+
+func (t *HTMLTemplates) load() error {
+	pages, err := t.glob(t.Pages)
+	if err != nil {
+		return err
+	}
+	layouts, err := t.glob(t.Layouts)
+	if err != nil {
+		return err
+	}
+	if len(pages) == 0 && len(layouts) == 0 {
+		return fmt.Errorf("goo: no templates matched %v", t.Pages)
+	}
+
+	// parsed aside so a failed reload keeps the last good templates
+	var shared *template.Template
+	var sets map[string]*template.Template
+	if len(layouts) == 0 {
+		shared, err = t.parse(template.New("").Funcs(t.FuncMap), pages...)
+		if err != nil {
+			return err
+		}
+	} else {
+		base, err := t.parse(template.New("").Funcs(t.FuncMap), layouts...)
+		if err != nil {
+			return err
+		}
+		sets = make(map[string]*template.Template, len(pages))
+		files := make(map[string]string, len(pages))
+		for _, page := range pages {
+			// templates are named after the file, not the directory
+			name := path.Base(filepath.ToSlash(page))
+			if other, ok := files[name]; ok {
+				return fmt.Errorf("goo: html pages %s and %s are both named %q", other, page, name)
+			}
+			files[name] = page
+			set, err := base.Clone()
+			if err != nil {
+				return err
+			}
+			if set, err = t.parse(set, page); err != nil {
+				return err
+			}
+			sets[name] = set
+		}
+	}
+	t.shared, t.sets = shared, sets
+	t.modTimes = t.stat(append(pages, layouts...))
+	return nil
+}
+
+// Render executes the named template, reloading it first in debug mode
+func (t *HTMLTemplates) Render(w io.Writer, name string, data interface{}) error {
+	if t.Debug {
+		if err := t.reloadIfChanged(); err != nil {
+			return err
+		}
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.shared != nil {
+		return t.shared.ExecuteTemplate(w, name, data)
+	}
+	set, ok := t.sets[name]
+	if !ok {
+		return fmt.Errorf("goo: html template %q is undefined", name)
+	}
+	return set.ExecuteTemplate(w, name, data)
+}
+
+func (t *HTMLTemplates) reloadIfChanged() error {
+	pages, err := t.glob(t.Pages)
+	if err != nil {
+		return err
+	}
+	layouts, err := t.glob(t.Layouts)
+	if err != nil {
+		return err
+	}
+	current := t.stat(append(pages, layouts...))
+
+	t.mu.RLock()
+	changed := !sameModTimes(current, t.modTimes)
+	t.mu.RUnlock()
+	if !changed {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.load()
+}
+
+func (t *HTMLTemplates) glob(patterns []string) ([]string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		var matches []string
+		var err error
+		if t.FS != nil {
+			matches, err = fs.Glob(t.FS, pattern)
+		} else {
+			matches, err = filepath.Glob(pattern)
+		}
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+func (t *HTMLTemplates) parse(set *template.Template, files ...string) (*template.Template, error) {
+	if t.FS != nil {
+		return set.ParseFS(t.FS, files...)
+	}
+	return set.ParseFiles(files...)
+}
+
+func (t *HTMLTemplates) stat(files []string) map[string]time.Time {
+	times := make(map[string]time.Time, len(files))
+	for _, file := range files {
+		var info fs.FileInfo
+		var err error
+		if t.FS != nil {
+			info, err = fs.Stat(t.FS, file)
+		} else {
+			info, err = os.Stat(file)
+		}
+		if err == nil {
+			times[file] = info.ModTime()
+		}
+	}
+	return times
+}
+
+func sameModTimes(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for file, t := range a {
+		if !b[file].Equal(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// urlCall is a {{url "name" params...}} call found in a template
+type urlCall struct {
+	template string
+	route    string
+	params   int
+}
+
+// urlCalls returns the url calls with a constant route name
+func (t *HTMLTemplates) urlCalls() []urlCall {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	sets := []*template.Template{t.shared}
+	if t.shared == nil {
+		sets = sets[:0]
+		for _, set := range t.sets {
+			sets = append(sets, set)
+		}
+	}
+	var calls []urlCall
+	for _, set := range sets {
+		for _, tmpl := range set.Templates() {
+			if tmpl.Tree != nil {
+				findURLCalls(tmpl.Name(), tmpl.Tree.Root, &calls)
+			}
+		}
+	}
+	return calls
+}
+
+func findURLCalls(name string, node parse.Node, calls *[]urlCall) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n != nil {
+			for _, child := range n.Nodes {
+				findURLCalls(name, child, calls)
+			}
+		}
+	case *parse.ActionNode:
+		findURLCalls(name, n.Pipe, calls)
+	case *parse.TemplateNode:
+		findURLCalls(name, n.Pipe, calls)
+	case *parse.IfNode:
+		findBranchURLCalls(name, &n.BranchNode, calls)
+	case *parse.RangeNode:
+		findBranchURLCalls(name, &n.BranchNode, calls)
+	case *parse.WithNode:
+		findBranchURLCalls(name, &n.BranchNode, calls)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for i, cmd := range n.Cmds {
+			if len(cmd.Args) >= 2 {
+				fn, isIdent := cmd.Args[0].(*parse.IdentifierNode)
+				route, isString := cmd.Args[1].(*parse.StringNode)
+				if isIdent && isString && fn.Ident == "url" {
+					call := urlCall{name, route.Text, len(cmd.Args) - 2}
+					if i > 0 {
+						call.params++ // the piped value
+					}
+					*calls = append(*calls, call)
+				}
+			}
+			for _, arg := range cmd.Args {
+				findURLCalls(name, arg, calls)
+			}
+		}
+	}
+}
+
+func findBranchURLCalls(name string, n *parse.BranchNode, calls *[]urlCall) {
+	findURLCalls(name, n.Pipe, calls)
+	findURLCalls(name, n.List, calls)
+	findURLCalls(name, n.ElseList, calls)
+}
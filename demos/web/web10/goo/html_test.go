@@ -0,0 +1,121 @@
+package goo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTemplate(t *testing.T, path string, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTMLLayout(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, filepath.Join(dir, "layouts", "base.tmpl"),
+		`<html>{{template "nav.tmpl"}}{{block "content" .}}empty{{end}}</html>`)
+	writeTemplate(t, filepath.Join(dir, "partials", "nav.tmpl"), `<nav></nav>`)
+	writeTemplate(t, filepath.Join(dir, "pages", "a.tmpl"),
+		`{{template "base.tmpl" .}}{{define "content"}}page a {{.}}{{end}}`)
+	writeTemplate(t, filepath.Join(dir, "pages", "b.tmpl"),
+		`{{template "base.tmpl" .}}{{define "content"}}page b{{end}}`)
+
+	tmpl := &HTMLTemplates{
+		Pages:   []string{filepath.Join(dir, "pages", "*")},
+		Layouts: []string{filepath.Join(dir, "layouts", "*"), filepath.Join(dir, "partials", "*")},
+	}
+	if err := tmpl.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if err := tmpl.Render(&out, "a.tmpl", "x"); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "<html><nav></nav>page a x</html>" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+
+	out.Reset()
+	if err := tmpl.Render(&out, "b.tmpl", nil); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "<html><nav></nav>page b</html>" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
+
+func TestHTMLDebugReload(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "hello.tmpl")
+	writeTemplate(t, file, "hello")
+
+	tmpl := &HTMLTemplates{Pages: []string{filepath.Join(dir, "*")}, Debug: true}
+	if err := tmpl.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	writeTemplate(t, file, "hello again")
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if err := tmpl.Render(&out, "hello.tmpl", nil); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "hello again" {
+		t.Fatalf("template should be reloaded, got %q", out.String())
+	}
+}
+
+func TestHTMLDebugReloadError(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "hello.tmpl")
+	writeTemplate(t, file, "hello")
+
+	tmpl := &HTMLTemplates{Pages: []string{filepath.Join(dir, "*")}, Debug: true}
+	if err := tmpl.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	writeTemplate(t, file, "hello {{")
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := tmpl.Render(&out, "hello.tmpl", nil); err == nil {
+		t.Fatalf("the parse error should be returned")
+	}
+
+	// the last good templates are kept
+	tmpl.Debug = false
+	out.Reset()
+	if err := tmpl.Render(&out, "hello.tmpl", nil); err != nil || out.String() != "hello" {
+		t.Fatalf("unexpected output %q %v", out.String(), err)
+	}
+}
+
+func TestHTMLLayoutDuplicatePages(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, filepath.Join(dir, "layouts", "base.tmpl"), `{{block "content" .}}{{end}}`)
+	writeTemplate(t, filepath.Join(dir, "admin", "index.tmpl"), `{{template "base.tmpl" .}}`)
+	writeTemplate(t, filepath.Join(dir, "shop", "index.tmpl"), `{{template "base.tmpl" .}}`)
+
+	tmpl := &HTMLTemplates{
+		Pages:   []string{filepath.Join(dir, "admin", "*"), filepath.Join(dir, "shop", "*")},
+		Layouts: []string{filepath.Join(dir, "layouts", "*")},
+	}
+	if err := tmpl.Load(); err == nil || !strings.Contains(err.Error(), `"index.tmpl"`) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
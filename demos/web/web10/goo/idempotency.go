@@ -0,0 +1,315 @@
+package goo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyRecord is the stored outcome of a request with an Idempotency-Key
+type IdempotencyRecord struct {
+	// RequestHash identifies the method, path and body of the request
+	RequestHash string
+	// Done is false while the first request is being handled
+	Done   bool
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// IdempotencyStore keeps idempotency records until their ttl expires,
+// implementations shared by several instances must make Reserve atomic
+type IdempotencyStore interface {
+	// Reserve stores record unless key exists, it returns the existing
+	// record and false otherwise
+	Reserve(key string, record *IdempotencyRecord, ttl time.Duration) (*IdempotencyRecord, bool, error)
+	Get(key string) (*IdempotencyRecord, error)
+	Save(key string, record *IdempotencyRecord, ttl time.Duration) error
+	Delete(key string) error
+}
+
+type memoryRecord struct {
+	record  *IdempotencyRecord
+	expires time.Time
+}
+
+// MemoryIdempotencyStore is an IdempotencyStore for a single instance
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]memoryRecord
+	swept   time.Time
+	now     func() time.Time
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{records: make(map[string]memoryRecord), now: time.Now}
+}
+
+// idempotencySweep is how often expired records of keys that aren't
+// used again are removed
+const idempotencySweep = time.Minute
+
+// sweep must be called with mu held
+func (s *MemoryIdempotencyStore) sweep(now time.Time) {
+	if now.Sub(s.swept) < idempotencySweep {
+		return
+	}
+	s.swept = now
+	for key, r := range s.records {
+		if now.After(r.expires) {
+			delete(s.records, key)
+		}
+	}
+}
+
+// get must be called with mu held
+func (s *MemoryIdempotencyStore) get(key string) *IdempotencyRecord {
+	r, ok := s.records[key]
+	if !ok {
+		return nil
+	}
+	if s.now().After(r.expires) {
+		delete(s.records, key)
+		return nil
+	}
+	copied := *r.record
+	return &copied
+}
+
+func (s *MemoryIdempotencyStore) Reserve(key string, record *IdempotencyRecord, ttl time.Duration) (*IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing := s.get(key); existing != nil {
+		return existing, false, nil
+	}
+	now := s.now()
+	s.sweep(now)
+	s.records[key] = memoryRecord{record, now.Add(ttl)}
+	return nil, true, nil
+}
+
+func (s *MemoryIdempotencyStore) Get(key string) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(key), nil
+}
+
+func (s *MemoryIdempotencyStore) Save(key string, record *IdempotencyRecord, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now)
+	s.records[key] = memoryRecord{record, now.Add(ttl)}
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// IdempotencyConfig configures the Idempotency middleware
+type IdempotencyConfig struct {
+	// Store keeps the responses, in memory by default
+	Store IdempotencyStore
+	// TTL is how long responses are replayed, 24h by default
+	TTL time.Duration
+	// Header carries the key, Idempotency-Key by default
+	Header string
+	// Methods are the methods keys are honoured for, POST and PATCH by default
+	Methods []string
+	// Wait is how long a duplicate of a request still being handled waits
+	// for its response, duplicates get 409 at once when it is 0
+	Wait time.Duration
+	// Scope separates the keys of different clients, e.g. by user id, so
+	// one can't replay the response of another. Keys are global when nil
+	Scope func(*Context) string
+	// MaxBody rejects larger request bodies with 413, 1 MB by default
+	MaxBody int64
+}
+
+// idempotencyUnstored are response headers that are never replayed,
+// cookies belong to one client and the nonce to one response
+var idempotencyUnstored = []string{"Set-Cookie", "Content-Security-Policy", "Content-Security-Policy-Report-Only"}
+
+// idempotencyWriter records the response while writing it
+type idempotencyWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *idempotencyWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *idempotencyWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *idempotencyWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Idempotency replays the stored response of requests repeating an
+// Idempotency-Key, marked with an Idempotent-Replayed header. A key
+// reused for a different request gets 422. Responses with 5xx status
+// aren't stored so the request can be retried. Only the headers set by
+// the handlers behind it are stored, not cookies or the CSP
+func Idempotency(config IdempotencyConfig) HandlerFunc {
+	if config.Store == nil {
+		config.Store = NewMemoryIdempotencyStore()
+	}
+	if config.TTL <= 0 {
+		config.TTL = 24 * time.Hour
+	}
+	if config.Header == "" {
+		config.Header = "Idempotency-Key"
+	}
+	if len(config.Methods) == 0 {
+		config.Methods = []string{http.MethodPost, http.MethodPatch}
+	}
+	if config.MaxBody <= 0 {
+		config.MaxBody = 1 << 20
+	}
+	methods := make(map[string]bool)
+	for _, method := range config.Methods {
+		methods[method] = true
+	}
+
+	return func(c *Context) {
+		idempotencyKey := c.Req.Header.Get(config.Header)
+		if idempotencyKey == "" || !methods[c.Method] {
+			c.Next()
+			return
+		}
+		// the body is hashed in memory, a *http.MaxBytesError is a 413
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Req.Body, config.MaxBody))
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Req.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.New()
+		io.WriteString(sum, c.Method+" "+c.Req.URL.RequestURI()+"\n")
+		sum.Write(body)
+		hash := hex.EncodeToString(sum.Sum(nil))
+		key := c.Method + " " + c.Req.URL.Path + " " + idempotencyKey
+		if config.Scope != nil {
+			key = config.Scope(c) + " " + key
+		}
+
+		var existing *IdempotencyRecord
+		reserved := false
+		for !reserved {
+			existing, reserved, err = config.Store.Reserve(key, &IdempotencyRecord{RequestHash: hash}, config.TTL)
+			if err != nil {
+				c.Error(err)
+				c.Abort()
+				return
+			}
+			if reserved || existing.RequestHash != hash || existing.Done {
+				break
+			}
+			if existing, err = waitIdempotency(c, config, key, existing); err != nil {
+				c.Error(err)
+				c.Abort()
+				return
+			}
+			// nil when the first request failed, this one takes over
+			if existing != nil {
+				break
+			}
+		}
+		if !reserved {
+			c.Abort()
+			if existing.RequestHash != hash {
+				c.Fail(http.StatusUnprocessableEntity, config.Header+" was used for a different request")
+				return
+			}
+			if !existing.Done {
+				c.Fail(http.StatusConflict, "a request with this "+config.Header+" is in progress")
+				return
+			}
+			header := c.Writer.Header()
+			for k, v := range existing.Header {
+				header[k] = v
+			}
+			header.Set("Idempotent-Replayed", "true")
+			c.Status(existing.Status)
+			c.Writer.Write(existing.Body)
+			return
+		}
+
+		before := c.Writer.Header().Clone()
+		w := &idempotencyWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		saved := false
+		defer func() {
+			c.Writer = w.ResponseWriter
+			if !saved {
+				// failed or panicked, let the client retry
+				config.Store.Delete(key)
+			}
+		}()
+		c.Next()
+		if w.status == 0 || w.status >= http.StatusInternalServerError {
+			return
+		}
+		record := &IdempotencyRecord{
+			RequestHash: hash,
+			Done:        true,
+			Status:      w.status,
+			Header:      make(http.Header),
+			Body:        w.body.Bytes(),
+		}
+		for k, v := range w.Header() {
+			if !equalValues(before[k], v) {
+				record.Header[k] = v
+			}
+		}
+		for _, k := range idempotencyUnstored {
+			record.Header.Del(k)
+		}
+		if err := config.Store.Save(key, record, config.TTL); err != nil {
+			c.Error(err)
+			return
+		}
+		saved = true
+	}
+}
+
+// waitIdempotency polls the store until the first request is done or
+// failed, then the record is nil. It returns record still in progress
+// when config.Wait passed or the client went away
+func waitIdempotency(c *Context, config IdempotencyConfig, key string, record *IdempotencyRecord) (*IdempotencyRecord, error) {
+	deadline := time.Now().Add(config.Wait)
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for time.Now().Before(deadline) {
+		select {
+		case <-ticker.C:
+		case <-c.Req.Context().Done():
+			return record, nil
+		}
+		latest, err := config.Store.Get(key)
+		if err != nil || latest == nil || latest.Done {
+			return latest, err
+		}
+	}
+	return record, nil
+}
@@ -0,0 +1,209 @@
+package goo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func postTodo(r *Engine, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/todo", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func newIdempotentEngine(config IdempotencyConfig, handler HandlerFunc) *Engine {
+	r := New()
+	r.Use(Idempotency(config))
+	r.POST("/todo", handler)
+	return r
+}
+
+func TestIdempotency(t *testing.T) {
+	var created int32
+	r := newIdempotentEngine(IdempotencyConfig{}, func(c *Context) {
+		n := atomic.AddInt32(&created, 1)
+		c.SetHeader("Location", "/todo/"+string(rune('0'+n)))
+		c.String(http.StatusCreated, "created %s", c.PostForm("title"))
+	})
+
+	first := postTodo(r, "k1", "")
+	second := postTodo(r, "k1", "")
+	if created != 1 {
+		t.Fatalf("the repeated request shouldn't be handled, %d todos", created)
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() ||
+		second.Header().Get("Location") != "/todo/1" || second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("unexpected replay %d %q %v", second.Code, second.Body.String(), second.Header())
+	}
+	if first.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatal("the first response isn't replayed")
+	}
+
+	if w := postTodo(r, "k1", "title=other"); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("unexpected status %d", w.Code)
+	}
+	postTodo(r, "k2", "")
+	postTodo(r, "", "")
+	if created != 3 {
+		t.Fatalf("unexpected %d todos", created)
+	}
+}
+
+func TestIdempotencyFailuresAreRetried(t *testing.T) {
+	var calls int32
+	r := newIdempotentEngine(IdempotencyConfig{}, func(c *Context) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			c.Fail(http.StatusServiceUnavailable, "db down")
+			return
+		}
+		c.String(http.StatusCreated, "created")
+	})
+	postTodo(r, "k", "")
+	if w := postTodo(r, "k", ""); w.Code != http.StatusCreated || calls != 2 {
+		t.Fatalf("unexpected response %d after %d calls", w.Code, calls)
+	}
+}
+
+func TestIdempotencyConcurrent(t *testing.T) {
+	for _, wait := range []time.Duration{0, time.Second} {
+		release := make(chan struct{})
+		started := make(chan struct{})
+		r := newIdempotentEngine(IdempotencyConfig{Wait: wait}, func(c *Context) {
+			close(started)
+			<-release
+			c.String(http.StatusCreated, "created")
+		})
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			postTodo(r, "k", "")
+		}()
+		<-started
+
+		duplicate := make(chan *httptest.ResponseRecorder)
+		go func() { duplicate <- postTodo(r, "k", "") }()
+		if wait > 0 {
+			time.Sleep(30 * time.Millisecond)
+			close(release)
+		}
+		w := <-duplicate
+		if wait == 0 {
+			close(release)
+			if w.Code != http.StatusConflict {
+				t.Fatalf("unexpected status %d", w.Code)
+			}
+		} else if w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "true" {
+			t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+		}
+		wg.Wait()
+	}
+}
+
+func TestIdempotencyScopeAndHeaders(t *testing.T) {
+	var created int32
+	r := New()
+	r.Use(Secure(DefaultSecureConfig()))
+	r.Use(Idempotency(IdempotencyConfig{Scope: func(c *Context) string { return c.Req.Header.Get("X-User") }}))
+	r.POST("/todo", func(c *Context) {
+		n := atomic.AddInt32(&created, 1)
+		c.SetCookie("session", c.Req.Header.Get("X-User"), 0, "", "", false, true)
+		c.String(http.StatusCreated, "created %d", n)
+	})
+	post := func(user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/todo", nil)
+		req.Header.Set("Idempotency-Key", "k")
+		req.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := post("alice")
+	if w := post("bob"); w.Body.String() != "created 2" {
+		t.Fatalf("bob got the response of alice %q", w.Body.String())
+	}
+	w := post("alice")
+	if w.Body.String() != "created 1" || w.Header().Get("Set-Cookie") != "" {
+		t.Fatalf("unexpected replay %q %v", w.Body.String(), w.Header())
+	}
+	if csp := w.Header().Values("Content-Security-Policy"); len(csp) != 1 || csp[0] == first.Header().Get("Content-Security-Policy") {
+		t.Fatalf("the replay should carry its own nonce, got %v", csp)
+	}
+}
+
+func TestIdempotencyMaxBody(t *testing.T) {
+	r := newIdempotentEngine(IdempotencyConfig{MaxBody: 4}, func(c *Context) {
+		c.String(http.StatusCreated, "created")
+	})
+	if w := postTodo(r, "k", "title=too long"); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("unexpected status %d", w.Code)
+	}
+}
+
+func TestIdempotencyWaiterTakesOver(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var calls int32
+	r := newIdempotentEngine(IdempotencyConfig{Wait: time.Second}, func(c *Context) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			<-release
+			c.Fail(http.StatusServiceUnavailable, "db down")
+			return
+		}
+		c.String(http.StatusCreated, "created")
+	})
+
+	go postTodo(r, "k", "")
+	<-started
+	duplicate := make(chan *httptest.ResponseRecorder)
+	go func() { duplicate <- postTodo(r, "k", "") }()
+	time.Sleep(30 * time.Millisecond)
+	close(release)
+	if w := <-duplicate; w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("the duplicate should be handled once the first failed, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestMemoryIdempotencyStoreTTL(t *testing.T) {
+	store := NewMemoryIdempotencyStore()
+	store.Save("k", &IdempotencyRecord{Done: true}, 10*time.Millisecond)
+	if record, _ := store.Get("k"); record == nil {
+		t.Fatal("the record should be stored")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, reserved, _ := store.Reserve("k", &IdempotencyRecord{}, time.Minute); !reserved {
+		t.Fatal("expired records should be replaced")
+	}
+}
+
+func TestMemoryIdempotencyStoreSweep(t *testing.T) {
+	now := time.Unix(1000, 0)
+	store := NewMemoryIdempotencyStore()
+	store.now = func() time.Time { return now }
+	store.Save("once", &IdempotencyRecord{Done: true}, time.Second)
+	store.Reserve("pending", &IdempotencyRecord{}, time.Second)
+
+	// keys that are never used again go away with the next sweep
+	now = now.Add(2 * time.Second)
+	store.Save("fresh", &IdempotencyRecord{Done: true}, time.Hour)
+	if len(store.records) != 3 {
+		t.Fatalf("swept too early, %d records", len(store.records))
+	}
+	now = now.Add(time.Minute)
+	store.Reserve("other", &IdempotencyRecord{}, time.Hour)
+	if len(store.records) != 2 {
+		t.Fatalf("unexpected %d records", len(store.records))
+	}
+}
@@ -11,7 +11,14 @@ func Logger() HandlerFunc {
 		t := time.Now()
 		// Process request
 		c.Next()
-		// Calculate resolution time
-		log.Printf("[%d] %s in %v", c.StatusCode, c.Req.RequestURI, time.Since(t))
+		// log once the engine rendered the errors, with their status
+		c.afterResponse = append(c.afterResponse, func() {
+			// Calculate resolution time
+			log.Printf("[%d] %s in %v", c.StatusCode, c.Req.RequestURI, time.Since(t))
+			// private details of the errors never reach the client
+			if len(c.Errors) > 0 {
+				log.Printf("%s", c.Errors)
+			}
+		})
 	}
 }
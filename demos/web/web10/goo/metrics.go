@@ -0,0 +1,62 @@
+package goo
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type metricSample struct {
+	name   string
+	help   string
+	typ    string // gauge or counter
+	labels map[string]string
+	value  float64
+}
+
+// MetricsSource is a component whose state Metrics exposes,
+// CircuitBreaker and Bulkhead are sources
+type MetricsSource interface {
+	metrics() []metricSample
+}
+
+// Metrics serves the state of sources in the Prometheus text format
+func Metrics(sources ...MetricsSource) HandlerFunc {
+	return func(c *Context) {
+		var samples []metricSample
+		for _, source := range sources {
+			samples = append(samples, source.metrics()...)
+		}
+		// samples of one metric are written together under one header
+		sort.SliceStable(samples, func(i, j int) bool { return samples[i].name < samples[j].name })
+
+		var b strings.Builder
+		for i, s := range samples {
+			if i == 0 || samples[i-1].name != s.name {
+				fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", s.name, s.help, s.name, s.typ)
+			}
+			b.WriteString(s.name)
+			if len(s.labels) > 0 {
+				keys := make([]string, 0, len(s.labels))
+				for k := range s.labels {
+					keys = append(keys, k)
+				}
+				sort.Strings(keys)
+				for j, k := range keys {
+					sep := ","
+					if j == 0 {
+						sep = "{"
+					}
+					fmt.Fprintf(&b, "%s%s=%s", sep, k, strconv.Quote(s.labels[k]))
+				}
+				b.WriteString("}")
+			}
+			fmt.Fprintf(&b, " %s\n", strconv.FormatFloat(s.value, 'g', -1, 64))
+		}
+		c.SetHeader("Content-Type", "text/plain; version=0.0.4")
+		c.Status(http.StatusOK)
+		c.Writer.Write([]byte(b.String()))
+	}
+}
@@ -0,0 +1,358 @@
+package goo
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenAPIInfo is the info object of the generated document
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+	// Host selects the routes of one virtual host, "" those without
+	// a host, since routes of different hosts may share their paths
+	Host string `json:"-"`
+}
+
+// routeDoc holds what Route's documentation methods add to the spec
+type routeDoc struct {
+	summary   string
+	tags      []string
+	body      reflect.Type
+	responses map[int]reflect.Type
+}
+
+func (r *Route) doc() *routeDoc {
+	if r.apiDoc == nil {
+		r.apiDoc = &routeDoc{responses: make(map[int]reflect.Type)}
+	}
+	return r.apiDoc
+}
+
+// Summary documents what the route does
+func (r *Route) Summary(summary string) *Route {
+	r.doc().summary = summary
+	return r
+}
+
+// Tags groups the route in the OpenAPI document
+func (r *Route) Tags(tags ...string) *Route {
+	r.doc().tags = append(r.doc().tags, tags...)
+	return r
+}
+
+// Body documents the json request body with the schema of obj,
+// usually the zero value of the struct the handler decodes into
+func (r *Route) Body(obj interface{}) *Route {
+	r.doc().body = reflect.TypeOf(obj)
+	return r
+}
+
+// Response documents the json response sent with code, obj may be nil
+func (r *Route) Response(code int, obj interface{}) *Route {
+	r.doc().responses[code] = reflect.TypeOf(obj)
+	return r
+}
+
+// openAPIPath turns /todo/:id<int> and /assets/*filepath into
+// /todo/{id} and /assets/{filepath} and returns the parameters,
+// constraints are the named ones of the router
+func openAPIPath(pattern string, constraints map[string]func(string) bool) (string, []H) {
+	var params []H
+	parts := parsePattern(pattern)
+	for i, part := range parts {
+		if part[0] == ':' || part[0] == '*' {
+			name, constraint := splitParam(part)
+			parts[i] = "{" + name + "}"
+			schema := H{"type": "string"}
+			switch constraint {
+			case "int":
+				schema = H{"type": "integer"}
+			case "uuid":
+				schema = H{"type": "string", "format": "uuid"}
+			case "":
+			default:
+				if pattern, ok := constraintPatterns[constraint]; ok {
+					schema["pattern"] = pattern
+				} else if _, ok := constraints[constraint]; !ok {
+					// an inline regular expression, registered ones are opaque
+					schema["pattern"] = "^(?:" + constraint + ")$"
+				}
+			}
+			params = append(params, H{"name": name, "in": "path", "required": true, "schema": schema})
+		}
+	}
+	return "/" + strings.Join(parts, "/"), params
+}
+
+// OpenAPI builds an OpenAPI 3.1 document from the routes of info.Host
+func (engine *Engine) OpenAPI(info OpenAPIInfo) H {
+	schemas := &schemaSet{types: make(map[string]map[reflect.Type]bool)}
+	var paths H
+	// the first pass finds the struct names used by several packages
+	for pass := 0; pass < 2; pass++ {
+		schemas.schemas, paths = H{}, H{}
+		for _, r := range engine.routes {
+			if r.Host != info.Host {
+				continue
+			}
+			// optional params aren't allowed in paths, document both variants
+			for _, pattern := range expandOptional(r.Pattern) {
+				openAPIOperation(r, pattern, paths, schemas)
+			}
+		}
+	}
+
+	doc := H{
+		"openapi": "3.1.0",
+		"info":    info,
+		"paths":   paths,
+	}
+	if len(schemas.schemas) > 0 {
+		doc["components"] = H{"schemas": schemas.schemas}
+	}
+	return doc
+}
+
+func openAPIOperation(r *Route, pattern string, paths H, schemas *schemaSet) {
+	path, params := openAPIPath(pattern, r.engine.routerFor(r.Host).constraints)
+	item, ok := paths[path].(H)
+	if !ok {
+		item = H{}
+		paths[path] = item
+	}
+
+	op := H{}
+	// operation ids are unique, only the variant with all params gets it
+	if r.Name != "" && pattern == strings.TrimSuffix(r.Pattern, "?") {
+		op["operationId"] = r.Name
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	responses := H{}
+	if d := r.apiDoc; d != nil {
+		if d.summary != "" {
+			op["summary"] = d.summary
+		}
+		if len(d.tags) > 0 {
+			op["tags"] = d.tags
+		}
+		if d.body != nil {
+			op["requestBody"] = H{
+				"required": true,
+				"content":  H{"application/json": H{"schema": jsonSchema(d.body, schemas)}},
+			}
+		}
+		for code, t := range d.responses {
+			response := H{"description": http.StatusText(code)}
+			if t != nil {
+				response["content"] = H{"application/json": H{"schema": jsonSchema(t, schemas)}}
+			}
+			responses[strconv.Itoa(code)] = response
+		}
+	}
+	if len(responses) == 0 {
+		responses["default"] = H{"description": "response"}
+	}
+	op["responses"] = responses
+	item[strings.ToLower(r.Method)] = op
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// jsonSchema reflects t the way encoding/json encodes it, named
+// structs go to components and are referenced with $ref
+func jsonSchema(t reflect.Type, schemas *schemaSet) H {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t, nullable = t.Elem(), true
+	}
+
+	var schema H
+	switch {
+	case t == timeType:
+		schema = H{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Bool:
+		schema = H{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		schema = H{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		schema = H{"type": "number"}
+	case t.Kind() == reflect.String:
+		schema = H{"type": "string"}
+	case (t.Kind() == reflect.Slice) && t.Elem().Kind() == reflect.Uint8:
+		schema = H{"type": "string", "contentEncoding": "base64"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		schema = H{"type": "array", "items": jsonSchema(t.Elem(), schemas)}
+	case t.Kind() == reflect.Map:
+		schema = H{"type": "object", "additionalProperties": jsonSchema(t.Elem(), schemas)}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		name := schemas.name(t)
+		if _, ok := schemas.schemas[name]; !ok {
+			schemas.schemas[name] = H{} // placeholder for recursive types
+			schemas.schemas[name] = structSchema(t, schemas)
+		}
+		schema = H{"$ref": "#/components/schemas/" + name}
+	case t.Kind() == reflect.Struct:
+		schema = structSchema(t, schemas)
+	default:
+		schema = H{}
+	}
+
+	if nullable {
+		if _, ok := schema["$ref"]; ok {
+			return H{"oneOf": []H{schema, {"type": "null"}}}
+		}
+		if typ, ok := schema["type"].(string); ok {
+			schema["type"] = []string{typ, "null"}
+		}
+	}
+	return schema
+}
+
+// schemaSet collects the schemas of the named structs of a document
+type schemaSet struct {
+	schemas H
+	types   map[string]map[reflect.Type]bool // by unqualified name
+}
+
+// name returns the component name of t, qualified with the package
+// path when structs of several packages have the same name
+func (s *schemaSet) name(t reflect.Type) string {
+	name := schemaName(t.Name())
+	if s.types[name] == nil {
+		s.types[name] = make(map[reflect.Type]bool)
+	}
+	s.types[name][t] = true
+	if len(s.types[name]) > 1 {
+		return schemaName(t.PkgPath() + "." + t.Name())
+	}
+	return name
+}
+
+func schemaName(name string) string {
+	// generic instantiations like Page[main.todo] and package paths
+	// aren't valid component names
+	return strings.NewReplacer("[", "_", "]", "", ".", "_", "*", "", ",", "_", " ", "", "/", "_").Replace(name)
+}
+
+func structSchema(t reflect.Type, schemas *schemaSet) H {
+	properties := H{}
+	var required []string
+	addFields(t, schemas, properties, &required)
+	schema := H{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func addFields(t reflect.Type, schemas *schemaSet, properties H, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addFields(ft, schemas, properties, required)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		schema := jsonSchema(f.Type, schemas)
+		if opts == "string" {
+			schema = H{"type": "string"}
+		}
+		if desc := f.Tag.Get("doc"); desc != "" {
+			schema["description"] = desc
+		}
+		properties[name] = schema
+		if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Ptr {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// OpenAPIHandler serves the OpenAPI document of the engine as json
+func OpenAPIHandler(info OpenAPIInfo) HandlerFunc {
+	return func(c *Context) {
+		c.JSON(http.StatusOK, c.engine.OpenAPI(info))
+	}
+}
+
+var openAPIViewer = template.Must(template.New("openapi").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>API</title>
+<style nonce="{{.Nonce}}">
+body { font-family: sans-serif; margin: 2em; }
+.op { border: 1px solid #ddd; border-radius: 4px; margin: .5em 0; padding: .5em; }
+.method { display: inline-block; width: 5em; font-weight: bold; text-transform: uppercase; }
+pre { background: #f6f6f6; padding: .5em; overflow: auto; }
+</style>
+</head>
+<body>
+<h1 id="title">API</h1>
+<div id="ops"></div>
+<script nonce="{{.Nonce}}">
+fetch({{.SpecURL}}).then(r => r.json()).then(spec => {
+  document.getElementById("title").textContent = spec.info.title + " " + spec.info.version;
+  const ops = document.getElementById("ops");
+  for (const [path, item] of Object.entries(spec.paths)) {
+    for (const [method, op] of Object.entries(item)) {
+      const div = document.createElement("details");
+      div.className = "op";
+      const summary = document.createElement("summary");
+      summary.innerHTML = '<span class="method"></span><code></code> <span></span>';
+      summary.children[0].textContent = method;
+      summary.children[1].textContent = path;
+      summary.children[2].textContent = op.summary || "";
+      const pre = document.createElement("pre");
+      pre.textContent = JSON.stringify(op, null, 2);
+      div.append(summary, pre);
+      ops.append(div);
+    }
+  }
+  const schemas = document.createElement("pre");
+  schemas.textContent = JSON.stringify((spec.components || {}).schemas || {}, null, 2);
+  ops.append(schemas);
+});
+</script>
+</body>
+</html>
+`))
+
+// OpenAPIViewer serves a small dependency-free page that lists the
+// operations of the document served at specURL, its inline script and
+// style carry the nonce of Secure's Content-Security-Policy
+func OpenAPIViewer(specURL string) HandlerFunc {
+	return func(c *Context) {
+		c.SetHeader("Content-Type", "text/html")
+		c.Status(http.StatusOK)
+		data := struct{ SpecURL, Nonce string }{specURL, c.CSPNonce()}
+		if err := openAPIViewer.Execute(c.Writer, data); err != nil {
+			fmt.Fprint(c.Writer, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package goo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type apiTodo struct {
+	ID      int        `json:"id"`
+	Title   string     `json:"title" doc:"what to do"`
+	Due     *time.Time `json:"due,omitempty"`
+	Tags    []string   `json:"tags"`
+	private bool
+}
+
+func TestOpenAPI(t *testing.T) {
+	r := New()
+	r.GET("/todo/:id", nil).Named("getTodo").Summary("get a todo").
+		Response(http.StatusOK, apiTodo{}).Response(http.StatusNotFound, nil)
+	r.POST("/todo", nil).Body(apiTodo{}).Response(http.StatusCreated, &apiTodo{})
+	r.GET("/openapi.json", OpenAPIHandler(OpenAPIInfo{Title: "todo", Version: "1.0"}))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/openapi.json", nil))
+	var doc map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	get := doc["paths"].(map[string]interface{})["/todo/{id}"].(map[string]interface{})["get"].(map[string]interface{})
+	if get["operationId"] != "getTodo" || get["parameters"].([]interface{})[0].(map[string]interface{})["name"] != "id" {
+		t.Fatalf("unexpected operation %v", get)
+	}
+
+	schema := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})["apiTodo"]
+	want := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":    map[string]interface{}{"type": "integer"},
+			"title": map[string]interface{}{"type": "string", "description": "what to do"},
+			"due":   map[string]interface{}{"type": []interface{}{"string", "null"}, "format": "date-time"},
+			"tags":  map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+		"required": []interface{}{"id", "title", "tags"},
+	}
+	if !reflect.DeepEqual(schema, want) {
+		t.Fatalf("unexpected schema %v", schema)
+	}
+}
+
+// Cookie has the name of http.Cookie
+type Cookie struct {
+	Flavor string `json:"flavor"`
+}
+
+func TestOpenAPINamesAndHosts(t *testing.T) {
+	r := New()
+	r.GET("/cookie", nil).Response(http.StatusOK, Cookie{})
+	r.GET("/http-cookie", nil).Response(http.StatusOK, http.Cookie{})
+	r.Host("admin.example.com").GET("/cookie", nil).Summary("admin cookie")
+
+	doc := r.OpenAPI(OpenAPIInfo{Title: "cookies", Version: "1.0"})
+	schemas := doc["components"].(H)["schemas"].(H)
+	if _, ok := schemas["goo_Cookie"]; !ok || len(schemas) != 2 {
+		t.Fatalf("unexpected schemas %v", reflect.ValueOf(schemas).MapKeys())
+	}
+	if _, ok := schemas["net_http_Cookie"]; !ok {
+		t.Fatalf("unexpected schemas %v", reflect.ValueOf(schemas).MapKeys())
+	}
+	if op := doc["paths"].(H)["/cookie"].(H)["get"].(H); op["summary"] != nil {
+		t.Fatalf("the admin host route replaced the default one %v", op)
+	}
+
+	doc = r.OpenAPI(OpenAPIInfo{Title: "admin", Version: "1.0", Host: "admin.example.com"})
+	if paths := doc["paths"].(H); len(paths) != 1 || paths["/cookie"].(H)["get"].(H)["summary"] != "admin cookie" {
+		t.Fatalf("unexpected paths %v", paths)
+	}
+}
+
+func TestOpenAPIViewerNonce(t *testing.T) {
+	r := New()
+	r.Use(Secure(DefaultSecureConfig()))
+	r.GET("/docs", OpenAPIViewer("/openapi.json"))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/docs", nil))
+	csp := w.Header().Get("Content-Security-Policy")
+	start := strings.Index(csp, "'nonce-") + len("'nonce-")
+	nonce := csp[start : start+strings.IndexByte(csp[start:], '\'')]
+	if !strings.Contains(w.Body.String(), `<script nonce="`+nonce+`">`) ||
+		!strings.Contains(w.Body.String(), `<style nonce="`+nonce+`">`) {
+		t.Fatalf("the viewer should carry the nonce %q", nonce)
+	}
+}
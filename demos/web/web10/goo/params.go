@@ -0,0 +1,190 @@
+package goo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ParamError reports a path, query or form value that can't be
+// converted to the requested type, it is rendered as json as is
+type ParamError struct {
+	Source string `json:"source"` // "path", "query" or "form"
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Type   string `json:"type"`
+	Err    error  `json:"-"`
+}
+
+func (e *ParamError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("%s parameter %q is required", e.Source, e.Key)
+	}
+	return fmt.Sprintf("%s parameter %q: %q is not a valid %s", e.Source, e.Key, e.Value, e.Type)
+}
+
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode is the response status of the error
+func (e *ParamError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+var errInvalidUUID = errors.New("invalid uuid")
+
+func parseInt(source, key, value string, bitSize int) (int64, error) {
+	n, err := strconv.ParseInt(value, 10, bitSize)
+	if err != nil {
+		return 0, &ParamError{Source: source, Key: key, Value: value, Type: "int", Err: err}
+	}
+	return n, nil
+}
+
+func parseUUID(source, key, value string) (string, error) {
+	if !isUUID(value) {
+		return "", &ParamError{Source: source, Key: key, Value: value, Type: "uuid", Err: errInvalidUUID}
+	}
+	return strings.ToLower(value), nil
+}
+
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, r := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return false
+			}
+		default:
+			if !('0' <= r && r <= '9' || 'a' <= r && r <= 'f' || 'A' <= r && r <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ParamInt returns the path parameter as an int
+func (c *Context) ParamInt(key string) (int, error) {
+	n, err := parseInt("path", key, c.Param(key), strconv.IntSize)
+	return int(n), err
+}
+
+// ParamInt64 returns the path parameter as an int64
+func (c *Context) ParamInt64(key string) (int64, error) {
+	return parseInt("path", key, c.Param(key), 64)
+}
+
+// ParamUUID returns the path parameter as a lowercase uuid string
+func (c *Context) ParamUUID(key string) (string, error) {
+	return parseUUID("path", key, c.Param(key))
+}
+
+func (c *Context) queryValues() url.Values {
+	if c.queryCache == nil {
+		c.queryCache = c.Req.URL.Query()
+	}
+	return c.queryCache
+}
+
+// GetQuery returns the query value and whether it was sent at all
+func (c *Context) GetQuery(key string) (string, bool) {
+	values, ok := c.queryValues()[key]
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// DefaultQuery returns the query value or defaultValue when it wasn't sent
+func (c *Context) DefaultQuery(key, defaultValue string) string {
+	if value, ok := c.GetQuery(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// QueryInt returns the query value as an int, defaultValue when it wasn't sent
+func (c *Context) QueryInt(key string, defaultValue int) (int, error) {
+	value, ok := c.GetQuery(key)
+	if !ok {
+		return defaultValue, nil
+	}
+	n, err := parseInt("query", key, value, strconv.IntSize)
+	return int(n), err
+}
+
+// QueryBool returns the query value as a bool, defaultValue when it wasn't sent
+func (c *Context) QueryBool(key string, defaultValue bool) (bool, error) {
+	value, ok := c.GetQuery(key)
+	if !ok {
+		return defaultValue, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, &ParamError{Source: "query", Key: key, Value: value, Type: "bool", Err: err}
+	}
+	return b, nil
+}
+
+// QueryArray returns every value of a repeated query key, ?tag=a&tag=b
+func (c *Context) QueryArray(key string) []string {
+	return c.queryValues()[key]
+}
+
+// QueryMap collects bracketed query keys, ?filter[done]=1&filter[tag]=go
+// gives {"done": "1", "tag": "go"} for the key "filter"
+func (c *Context) QueryMap(key string) map[string]string {
+	return bracketMap(c.queryValues(), key)
+}
+
+func bracketMap(values url.Values, key string) map[string]string {
+	m := make(map[string]string)
+	for k, v := range values {
+		if i := strings.IndexByte(k, '['); i > 0 && k[:i] == key && strings.HasSuffix(k, "]") && len(v) > 0 {
+			m[k[i+1:len(k)-1]] = v[0]
+		}
+	}
+	return m
+}
+
+func (c *Context) postFormValues() url.Values {
+	if c.Req.PostForm == nil {
+		// parses urlencoded bodies too, file type errors are left to FormFile
+		c.MultipartForm()
+	}
+	return c.Req.PostForm
+}
+
+// GetPostFormArray returns every value of a repeated form field
+// and whether it was sent at all
+func (c *Context) GetPostFormArray(key string) ([]string, bool) {
+	values, ok := c.postFormValues()[key]
+	return values, ok
+}
+
+// PostFormArray returns every value of a repeated form field
+func (c *Context) PostFormArray(key string) []string {
+	values, _ := c.GetPostFormArray(key)
+	return values
+}
+
+// DefaultPostForm returns the form value or defaultValue when it wasn't sent
+func (c *Context) DefaultPostForm(key, defaultValue string) string {
+	if values, ok := c.GetPostFormArray(key); ok && len(values) > 0 {
+		return values[0]
+	}
+	return defaultValue
+}
+
+// PostFormMap collects bracketed form fields like QueryMap
+func (c *Context) PostFormMap(key string) map[string]string {
+	return bracketMap(c.postFormValues(), key)
+}
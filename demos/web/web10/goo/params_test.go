@@ -0,0 +1,59 @@
+package goo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTypedParams(t *testing.T) {
+	r := New()
+	r.GET("/todo/:id", func(c *Context) {
+		id, err := c.ParamInt("id")
+		var paramErr *ParamError
+		if errors.As(err, &paramErr) {
+			c.JSON(paramErr.StatusCode(), paramErr)
+			return
+		}
+		page, _ := c.QueryInt("page", 1)
+		c.JSON(http.StatusOK, H{
+			"id":     id,
+			"page":   page,
+			"sort":   c.DefaultQuery("sort", "title"),
+			"tags":   c.QueryArray("tag"),
+			"filter": c.QueryMap("filter"),
+		})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/todo/42?tag=a&tag=b&filter[done]=1&filter[owner]=me", nil))
+	want := `{"filter":{"done":"1","owner":"me"},"id":42,"page":1,"sort":"title","tags":["a","b"]}` + "\n"
+	if w.Body.String() != want {
+		t.Fatalf("unexpected params %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/todo/abc", nil))
+	want = `{"source":"path","key":"id","value":"abc","type":"int"}` + "\n"
+	if w.Code != http.StatusBadRequest || w.Body.String() != want {
+		t.Fatalf("unexpected error response %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestPostFormArray(t *testing.T) {
+	r := New()
+	r.POST("/todo", func(c *Context) {
+		tags, ok := c.GetPostFormArray("tag")
+		c.JSON(http.StatusOK, H{"tags": tags, "ok": ok, "title": c.DefaultPostForm("title", "untitled")})
+	})
+
+	req := httptest.NewRequest("POST", "/todo", strings.NewReader("tag=go&tag=web"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Body.String() != `{"ok":true,"tags":["go","web"],"title":"untitled"}`+"\n" {
+		t.Fatalf("unexpected form values %q", w.Body.String())
+	}
+}
@@ -0,0 +1,311 @@
+package goo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Balance picks the upstream target of a proxied request
+type Balance int
+
+const (
+	// RoundRobin takes the healthy targets in turn
+	RoundRobin Balance = iota
+	// LeastConnections takes the target with the fewest requests in flight
+	LeastConnections
+	// ConsistentHash keeps requests with the same ProxyConfig.HashKey on
+	// the same target while the set of healthy targets doesn't change
+	ConsistentHash
+)
+
+// HealthCheck probes every target with a GET request, targets answering
+// with an error or a status of 400 and above get no requests until
+// they pass again
+type HealthCheck struct {
+	Path     string
+	Interval time.Duration // 10s by default
+	Timeout  time.Duration // 2s by default
+}
+
+// ProxyConfig configures a reverse Proxy
+type ProxyConfig struct {
+	// Targets are the upstream base urls, like http://api:9999
+	Targets []string
+	Balance Balance
+	// HashKey is the key of ConsistentHash, the client ip by default
+	HashKey func(*http.Request) string
+	// HealthCheck is off when Path is empty. Without it a target that
+	// fails a request is still used
+	HealthCheck HealthCheck
+	// Retries is how often requests with idempotent methods and no body
+	// are retried on another target when the connection fails
+	Retries int
+	// PreserveHost forwards the Host header of the client, otherwise
+	// the host of the target is sent
+	PreserveHost bool
+	// RequestHeaders and ResponseHeaders are set on the forwarded request
+	// and on the response, empty values remove the header
+	RequestHeaders  map[string]string
+	ResponseHeaders map[string]string
+	// LocationPrefix is prepended to absolute paths in the Location header
+	// of upstream redirects, for targets mounted under a prefix
+	LocationPrefix string
+	// Transport sends the requests, http.DefaultTransport by default
+	Transport http.RoundTripper
+}
+
+type proxyTarget struct {
+	url     *url.URL
+	proxy   *httputil.ReverseProxy
+	active  int64 // requests in flight
+	healthy atomic.Bool
+}
+
+// Proxy forwards requests to upstream targets, it is an http.Handler
+// so a group forwards everything below a prefix with Mount:
+//
+//	r.Mount("/todo", proxy)
+//
+// Upgraded connections like websockets are passed through
+type Proxy struct {
+	config  ProxyConfig
+	targets []*proxyTarget
+	next    uint64
+	ring    []ringPoint // for ConsistentHash
+	stop    chan struct{}
+	once    sync.Once
+}
+
+type ringPoint struct {
+	hash   uint32
+	target *proxyTarget
+}
+
+// replicas of each target on the ConsistentHash ring
+const ringReplicas = 100
+
+type proxyAttemptKey struct{}
+
+// proxyAttempt lets the error handler of a target tell the Proxy that
+// the request failed, so it can be retried before anything is written
+type proxyAttempt struct {
+	err   error
+	final bool
+}
+
+var errNoTarget = errors.New("goo: no healthy proxy target")
+
+// NewProxy parses the targets and starts the health checks,
+// Close stops them
+func NewProxy(config ProxyConfig) (*Proxy, error) {
+	if len(config.Targets) == 0 {
+		return nil, errors.New("goo: proxy needs a target")
+	}
+	if config.Transport == nil {
+		config.Transport = http.DefaultTransport
+	}
+	if config.HashKey == nil {
+		config.HashKey = clientIP
+	}
+	p := &Proxy{config: config, stop: make(chan struct{})}
+	for _, raw := range config.Targets {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("goo: proxy target %q: %w", raw, err)
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("goo: proxy target %q needs a scheme and host", raw)
+		}
+		t := &proxyTarget{url: u}
+		t.healthy.Store(true)
+		t.proxy = p.reverseProxy(u)
+		p.targets = append(p.targets, t)
+		for i := 0; i < ringReplicas; i++ {
+			p.ring = append(p.ring, ringPoint{crc32.ChecksumIEEE([]byte(raw + "#" + strconv.Itoa(i))), t})
+		}
+	}
+	sort.Slice(p.ring, func(i, j int) bool { return p.ring[i].hash < p.ring[j].hash })
+
+	if config.HealthCheck.Path != "" {
+		go p.checkHealth()
+	}
+	return p, nil
+}
+
+func clientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+func setHeaders(header http.Header, values map[string]string) {
+	for k, v := range values {
+		if v == "" {
+			header.Del(k)
+		} else {
+			header.Set(k, v)
+		}
+	}
+}
+
+func (p *Proxy) reverseProxy(target *url.URL) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			if p.config.PreserveHost {
+				pr.Out.Host = pr.In.Host
+			}
+			setHeaders(pr.Out.Header, p.config.RequestHeaders)
+		},
+		Transport:     p.config.Transport,
+		FlushInterval: -1, // stream responses like server-sent events
+		ModifyResponse: func(res *http.Response) error {
+			if location := res.Header.Get("Location"); p.config.LocationPrefix != "" && strings.HasPrefix(location, "/") {
+				res.Header.Set("Location", strings.TrimSuffix(p.config.LocationPrefix, "/")+location)
+			}
+			setHeaders(res.Header, p.config.ResponseHeaders)
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			attempt, _ := req.Context().Value(proxyAttemptKey{}).(*proxyAttempt)
+			if attempt != nil {
+				attempt.err = err
+				if !attempt.final {
+					return
+				}
+			}
+			log.Printf("[proxy] %s %s: %v", req.Method, target.Host, err)
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+}
+
+func (p *Proxy) checkHealth() {
+	interval, timeout := p.config.HealthCheck.Interval, p.config.HealthCheck.Timeout
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	client := &http.Client{Transport: p.config.Transport, Timeout: timeout}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		var wg sync.WaitGroup
+		for _, t := range p.targets {
+			wg.Add(1)
+			go func(t *proxyTarget) {
+				defer wg.Done()
+				res, err := client.Get(t.url.JoinPath(p.config.HealthCheck.Path).String())
+				healthy := err == nil && res.StatusCode < http.StatusBadRequest
+				if err == nil {
+					res.Body.Close()
+				}
+				if t.healthy.Swap(healthy) != healthy {
+					log.Printf("[proxy] target %s healthy: %v", t.url.Host, healthy)
+				}
+			}(t)
+		}
+		wg.Wait()
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close stops the health checks
+func (p *Proxy) Close() {
+	p.once.Do(func() { close(p.stop) })
+}
+
+// pick returns a healthy target that wasn't tried yet
+func (p *Proxy) pick(req *http.Request, tried map[*proxyTarget]bool) *proxyTarget {
+	usable := func(t *proxyTarget) bool {
+		return t.healthy.Load() && !tried[t]
+	}
+	switch p.config.Balance {
+	case LeastConnections:
+		var best *proxyTarget
+		for _, t := range p.targets {
+			if usable(t) && (best == nil || atomic.LoadInt64(&t.active) < atomic.LoadInt64(&best.active)) {
+				best = t
+			}
+		}
+		return best
+	case ConsistentHash:
+		hash := crc32.ChecksumIEEE([]byte(p.config.HashKey(req)))
+		i := sort.Search(len(p.ring), func(i int) bool { return p.ring[i].hash >= hash })
+		for j := 0; j < len(p.ring); j++ {
+			if t := p.ring[(i+j)%len(p.ring)].target; usable(t) {
+				return t
+			}
+		}
+		return nil
+	default:
+		n := atomic.AddUint64(&p.next, 1)
+		for j := 0; j < len(p.targets); j++ {
+			if t := p.targets[(int(n)+j)%len(p.targets)]; usable(t) {
+				return t
+			}
+		}
+		return nil
+	}
+}
+
+func retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		// the body was consumed by the failed attempt
+		return req.Body == nil || req.Body == http.NoBody || req.ContentLength == 0 && len(req.TransferEncoding) == 0
+	}
+	return false
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	attempts := 1
+	if retryable(req) {
+		attempts += p.config.Retries
+	}
+	tried := make(map[*proxyTarget]bool)
+	for i := 0; i < attempts; i++ {
+		t := p.pick(req, tried)
+		if t == nil {
+			break
+		}
+		tried[t] = true
+		attempt := &proxyAttempt{final: i == attempts-1 || len(tried) == len(p.targets)}
+		ctx := context.WithValue(req.Context(), proxyAttemptKey{}, attempt)
+
+		atomic.AddInt64(&t.active, 1)
+		t.proxy.ServeHTTP(w, req.WithContext(ctx))
+		atomic.AddInt64(&t.active, -1)
+		if attempt.err == nil || attempt.final {
+			return
+		}
+		if p.config.HealthCheck.Path != "" && req.Context().Err() == nil {
+			// passive check, the next active check may revive it
+			t.healthy.Store(false)
+		}
+	}
+	log.Printf("[proxy] %s %s: %v", req.Method, req.URL.Path, errNoTarget)
+	w.WriteHeader(http.StatusBadGateway)
+}
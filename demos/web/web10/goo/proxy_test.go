@@ -0,0 +1,171 @@
+package goo
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newUpstream(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/health":
+			if name == "sick" {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		case "/redirect":
+			http.Redirect(w, req, "/login", http.StatusFound)
+		default:
+			w.Header().Set("Server", "upstream")
+			fmt.Fprintf(w, "%s %s %s %s", name, req.URL.Path, req.Header.Get("X-Forwarded-For"), req.Header.Get("X-Env"))
+		}
+	}))
+}
+
+func proxyGet(t *testing.T, server *httptest.Server, path string) (*http.Response, string) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	res, err := client.Get(server.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	return res, string(body)
+}
+
+func TestProxy(t *testing.T) {
+	a, b := newUpstream("a"), newUpstream("b")
+	defer a.Close()
+	defer b.Close()
+	proxy, err := NewProxy(ProxyConfig{
+		Targets:         []string{a.URL, b.URL},
+		RequestHeaders:  map[string]string{"X-Env": "test"},
+		ResponseHeaders: map[string]string{"Server": ""},
+		LocationPrefix:  "/todo",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New()
+	r.Mount("/todo", proxy)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	_, first := proxyGet(t, server, "/todo/list")
+	res, second := proxyGet(t, server, "/todo/list")
+	if !strings.HasSuffix(first, " /list 127.0.0.1 test") || first[0] == second[0] {
+		t.Fatalf("unexpected bodies %q %q", first, second)
+	}
+	if res.Header.Get("Server") != "" {
+		t.Fatalf("unexpected headers %v", res.Header)
+	}
+	if res, _ = proxyGet(t, server, "/todo/redirect"); res.Header.Get("Location") != "/todo/login" {
+		t.Fatalf("unexpected location %q", res.Header.Get("Location"))
+	}
+}
+
+func TestProxyRetry(t *testing.T) {
+	a, dead := newUpstream("a"), newUpstream("dead")
+	defer a.Close()
+	dead.Close()
+	proxy, _ := NewProxy(ProxyConfig{Targets: []string{a.URL, dead.URL}, Retries: 1})
+	r := New()
+	r.Mount("/", proxy)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	for i := 0; i < 4; i++ {
+		if res, body := proxyGet(t, server, "/"); res.StatusCode != http.StatusOK || body[0] != 'a' {
+			t.Fatalf("unexpected response %d %q", res.StatusCode, body)
+		}
+	}
+
+	codes := map[int]int{}
+	for i := 0; i < 4; i++ {
+		res, err := http.Post(server.URL+"/", "text/plain", strings.NewReader("not idempotent"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		codes[res.StatusCode]++
+	}
+	if codes[http.StatusOK] != 2 || codes[http.StatusBadGateway] != 2 {
+		t.Fatalf("POSTs shouldn't be retried, got %v", codes)
+	}
+}
+
+func TestProxyHealthCheck(t *testing.T) {
+	a, sick := newUpstream("a"), newUpstream("sick")
+	defer a.Close()
+	defer sick.Close()
+	proxy, _ := NewProxy(ProxyConfig{
+		Targets:     []string{a.URL, sick.URL},
+		HealthCheck: HealthCheck{Path: "/health", Interval: 10 * time.Millisecond},
+	})
+	defer proxy.Close()
+	time.Sleep(50 * time.Millisecond)
+	if proxy.targets[1].healthy.Load() || !proxy.targets[0].healthy.Load() {
+		t.Fatal("the sick target should be marked unhealthy")
+	}
+
+	r := New()
+	r.GET("/", WrapH(proxy))
+	server := httptest.NewServer(r)
+	defer server.Close()
+	for i := 0; i < 4; i++ {
+		if _, body := proxyGet(t, server, "/"); body[0] != 'a' {
+			t.Fatalf("unexpected body %q", body)
+		}
+	}
+}
+
+func TestProxyBalance(t *testing.T) {
+	targets := []string{"http://a", "http://b", "http://c"}
+	proxy, _ := NewProxy(ProxyConfig{Targets: targets, Balance: LeastConnections})
+	proxy.targets[0].active, proxy.targets[1].active, proxy.targets[2].active = 3, 1, 2
+	if got := proxy.pick(httptest.NewRequest("GET", "/", nil), nil); got != proxy.targets[1] {
+		t.Fatalf("unexpected target %s", got.url)
+	}
+
+	proxy, _ = NewProxy(ProxyConfig{Targets: targets, Balance: ConsistentHash,
+		HashKey: func(req *http.Request) string { return req.URL.Query().Get("user") }})
+	picked := map[string]*proxyTarget{}
+	for i := 0; i < 50; i++ {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/?user=%d", i), nil)
+		picked[req.URL.RawQuery] = proxy.pick(req, nil)
+		if proxy.pick(req, nil) != picked[req.URL.RawQuery] {
+			t.Fatal("the same key should get the same target")
+		}
+	}
+	proxy.targets[0].healthy.Store(false)
+	for key, before := range picked {
+		after := proxy.pick(httptest.NewRequest("GET", "/?"+key, nil), nil)
+		if after == proxy.targets[0] || before != proxy.targets[0] && after != before {
+			t.Fatalf("key %s moved from %s to %s", key, before.url, after.url)
+		}
+	}
+}
+
+func TestProxyWebSocket(t *testing.T) {
+	upstream := newEchoServer()
+	defer upstream.Close()
+	proxy, _ := NewProxy(ProxyConfig{Targets: []string{upstream.URL}})
+	r := New()
+	r.GET("/ws", WrapH(proxy))
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	tc, status := dialTestWebSocket(t, server, "")
+	defer tc.conn.Close()
+	if status != "101 Switching Protocols s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected handshake %q", status)
+	}
+	tc.writeFrame(true, TextMessage, []byte("hello"))
+	if opcode, payload := tc.readFrame(t); opcode != TextMessage || string(payload) != "hello" {
+		t.Fatalf("unexpected frame %d %q", opcode, payload)
+	}
+}
@@ -0,0 +1,132 @@
+package goo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newRedirectEngine() *Engine {
+	r := New()
+	r.RedirectTrailingSlash = true
+	r.RedirectFixedPath = true
+	r.RemoveExtraSlash = true
+	ok := func(c *Context) { c.String(http.StatusOK, c.Path) }
+	r.GET("/hello", ok)
+	r.GET("/dir/", ok)
+	r.POST("/todo/:id<int>", ok)
+	r.GET("/Users/:name/Profile", ok)
+	return r
+}
+
+func TestPathRedirects(t *testing.T) {
+	r := newRedirectEngine()
+	tests := []struct {
+		method, path string
+		code         int
+		location     string
+	}{
+		{"GET", "/hello", http.StatusOK, ""},
+		{"GET", "/hello/", http.StatusMovedPermanently, "/hello"},
+		{"GET", "/dir", http.StatusMovedPermanently, "/dir/"},
+		{"GET", "/dir/", http.StatusOK, ""},
+		{"GET", "//hello", http.StatusMovedPermanently, "/hello"},
+		{"GET", "/x/../HELLO?a=1", http.StatusMovedPermanently, "/hello?a=1"},
+		{"GET", "/users/Gootutu/profile", http.StatusMovedPermanently, "/Users/Gootutu/Profile"},
+		{"POST", "/todo/42/", http.StatusPermanentRedirect, "/todo/42"},
+		{"POST", "/TODO/abc", http.StatusNotFound, ""},
+		{"GET", "/nothing", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "http://example.com", nil)
+		req.URL.Path, req.URL.RawQuery, _ = strings.Cut(tt.path, "?")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.code || w.Header().Get("Location") != tt.location {
+			t.Fatalf("unexpected response for %s %s: %d %q", tt.method, tt.path, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
+
+func TestTrailingSlashOpenRedirect(t *testing.T) {
+	r := New()
+	r.RedirectTrailingSlash = true
+	r.GET("/:user", func(c *Context) { c.String(http.StatusOK, c.Param("user")) })
+	for _, path := range []string{"//evil.com/", "///evil.com/", "/\\evil.com/"} {
+		req := httptest.NewRequest("GET", "http://example.com", nil)
+		req.URL.Path = path
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		location := w.Header().Get("Location")
+		if strings.HasPrefix(location, "//") || strings.HasPrefix(location, "/\\") {
+			t.Fatalf("%s redirected to %q", path, location)
+		}
+	}
+}
+
+func TestTrailingSlashKeepsRoute(t *testing.T) {
+	r := New()
+	r.RedirectTrailingSlash = true
+	r.GET("/", func(c *Context) { c.String(http.StatusOK, "home") })
+	r.GET("/profile", func(c *Context) { c.String(http.StatusOK, "profile") })
+	r.GET("/user/:name/profile", func(c *Context) { c.String(http.StatusOK, "user") })
+	r.GET("/files/:name", func(c *Context) { c.String(http.StatusOK, "file") })
+	tests := []struct {
+		path, location string
+	}{
+		{"/user/../profile", ""},
+		{"/user/../profile/", ""},
+		{"/files/../", ""},
+		{"/user//bob/profile/", "/user//bob/profile"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if location := w.Header().Get("Location"); location != test.location {
+			t.Fatalf("%s redirected to %q, want %q", test.path, location, test.location)
+		}
+	}
+}
+
+func TestPathRedirectsDisabled(t *testing.T) {
+	r := New()
+	r.GET("/hello", func(c *Context) { c.String(http.StatusOK, "hello") })
+	for _, path := range []string{"/hello/", "//hello"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected status for %s: %d", path, w.Code)
+		}
+	}
+}
+
+func TestOptionalParam(t *testing.T) {
+	r := New()
+	r.GET("/files/:name?", func(c *Context) {
+		c.String(http.StatusOK, "[%s]", c.Param("name"))
+	}).Named("files")
+
+	for path, body := range map[string]string{"/files": "[]", "/files/a.txt": "[a.txt]"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Body.String() != body {
+			t.Fatalf("unexpected body for %s: %q", path, w.Body.String())
+		}
+	}
+
+	if u, _ := r.URL("files"); u != "/files" {
+		t.Fatalf("unexpected url %q", u)
+	}
+	if u, _ := r.URL("files", "a.txt"); u != "/files/a.txt" {
+		t.Fatalf("unexpected url %q", u)
+	}
+
+	paths := r.OpenAPI(OpenAPIInfo{})["paths"].(H)
+	if _, ok := paths["/files"]; !ok {
+		t.Fatalf("unexpected paths %v", paths)
+	}
+	if _, ok := paths["/files/{name}"]; !ok {
+		t.Fatalf("unexpected paths %v", paths)
+	}
+}
@@ -0,0 +1,356 @@
+package goo
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Renderer writes an object in one response format, register your own
+// with Engine.RegisterRenderer to make it available to Context.Negotiate
+type Renderer interface {
+	ContentType() string
+	Render(w io.Writer, obj interface{}) error
+}
+
+type jsonRender struct{}
+
+func (jsonRender) ContentType() string { return "application/json" }
+
+func (jsonRender) Render(w io.Writer, obj interface{}) error {
+	return json.NewEncoder(w).Encode(obj)
+}
+
+type indentedJSONRender struct{}
+
+func (indentedJSONRender) ContentType() string { return "application/json" }
+
+func (indentedJSONRender) Render(w io.Writer, obj interface{}) error {
+	data, err := json.MarshalIndent(obj, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// secureJSONRender prefixes arrays to prevent json hijacking
+type secureJSONRender struct {
+	prefix string
+}
+
+func (secureJSONRender) ContentType() string { return "application/json" }
+
+func (r secureJSONRender) Render(w io.Writer, obj interface{}) error {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	if bytes.HasPrefix(data, []byte("[")) {
+		if _, err = io.WriteString(w, r.prefix); err != nil {
+			return err
+		}
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// asciiJSONRender escapes every non-ASCII character as \uXXXX
+type asciiJSONRender struct{}
+
+func (asciiJSONRender) ContentType() string { return "application/json" }
+
+func (asciiJSONRender) Render(w io.Writer, obj interface{}) error {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, r := range string(data) {
+		if r < 0x80 {
+			buf.WriteRune(r)
+		} else if r > 0xffff {
+			r1, r2 := surrogates(r)
+			fmt.Fprintf(&buf, `\u%04x\u%04x`, r1, r2)
+		} else {
+			fmt.Fprintf(&buf, `\u%04x`, r)
+		}
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func surrogates(r rune) (rune, rune) {
+	r -= 0x10000
+	return 0xd800 + (r>>10)&0x3ff, 0xdc00 + r&0x3ff
+}
+
+type jsonpRender struct {
+	callback string
+}
+
+func (jsonpRender) ContentType() string { return "application/javascript" }
+
+func (r jsonpRender) Render(w io.Writer, obj interface{}) error {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s(%s);", r.callback, data)
+	return err
+}
+
+// jsonpCallback matches the identifiers and dotted paths a callback may be
+var jsonpCallback = regexp.MustCompile(`^[A-Za-z_$][0-9A-Za-z_$]*(\.[A-Za-z_$][0-9A-Za-z_$]*)*$`)
+
+type xmlRender struct{}
+
+func (xmlRender) ContentType() string { return "application/xml" }
+
+func (xmlRender) Render(w io.Writer, obj interface{}) error {
+	// maps can't be encoded by encoding/xml, write H as <map><key>value</key></map>
+	if m, ok := xmlValue(obj).(xmlMap); ok {
+		return xml.NewEncoder(w).EncodeElement(m, xml.StartElement{Name: xml.Name{Local: "map"}})
+	}
+	return xml.NewEncoder(w).Encode(obj)
+}
+
+// xmlValue turns H and map[string]interface{} into xmlMap, also inside slices
+func xmlValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case H:
+		return xmlMap(v)
+	case map[string]interface{}:
+		return xmlMap(v)
+	case []H:
+		values := make([]interface{}, len(v))
+		for i, h := range v {
+			values[i] = xmlMap(h)
+		}
+		return values
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, value := range v {
+			values[i] = xmlValue(value)
+		}
+		return values
+	}
+	return v
+}
+
+type xmlMap map[string]interface{}
+
+func (m xmlMap) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		if !isXMLName(key) {
+			return fmt.Errorf("goo: xml: invalid element name %q", key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := e.EncodeElement(xmlValue(m[key]), xml.StartElement{Name: xml.Name{Local: key}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// isXMLName reports whether name can be an element name without a namespace
+func isXMLName(name string) bool {
+	if name == "" || len(name) >= 3 && strings.EqualFold(name[:3], "xml") {
+		return false
+	}
+	for i, r := range name {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r) && r != '-' && r != '.') {
+			return false
+		}
+	}
+	return true
+}
+
+type yamlRender struct{}
+
+func (yamlRender) ContentType() string { return "application/yaml" }
+
+func (yamlRender) Render(w io.Writer, obj interface{}) error {
+	return encodeYAML(w, obj)
+}
+
+func defaultRenderers() []Renderer {
+	return []Renderer{jsonRender{}, xmlRender{}, yamlRender{}}
+}
+
+// RegisterRenderer makes r available to Context.Negotiate,
+// it replaces the renderer registered for the same content type
+func (engine *Engine) RegisterRenderer(r Renderer) {
+	for i, old := range engine.renderers {
+		if mediaType(old.ContentType()) == mediaType(r.ContentType()) {
+			engine.renderers[i] = r
+			return
+		}
+	}
+	engine.renderers = append(engine.renderers, r)
+}
+
+// SetSecureJSONPrefix changes the prefix written by Context.SecureJSON
+func (engine *Engine) SetSecureJSONPrefix(prefix string) {
+	engine.secureJSONPrefix = prefix
+}
+
+func (engine *Engine) renderer(contentType string) Renderer {
+	for _, r := range engine.renderers {
+		if mediaType(r.ContentType()) == contentType {
+			return r
+		}
+	}
+	return nil
+}
+
+func mediaType(contentType string) string {
+	if t, _, err := mime.ParseMediaType(contentType); err == nil {
+		return t
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+// negotiate returns the offer the Accept header prefers, the first offer
+// when the header is empty and "" when nothing is acceptable
+func negotiate(accept string, offers []string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	type mediaRange struct {
+		value string
+		q     float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		value, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{value, q})
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		offerType := mediaType(offer)
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			s := -1
+			switch {
+			case r.value == offerType:
+				s = 2
+			case strings.HasSuffix(r.value, "/*") && strings.HasPrefix(offerType, strings.TrimSuffix(r.value, "*")):
+				s = 1
+			case r.value == "*/*":
+				s = 0
+			}
+			if s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// Render writes obj with r, nothing is sent when rendering fails
+func (c *Context) Render(code int, r Renderer, obj interface{}) {
+	var buf bytes.Buffer
+	if err := r.Render(&buf, obj); err != nil {
+		c.Fail(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.SetHeader("Content-Type", r.ContentType())
+	c.Status(code)
+	c.Writer.Write(buf.Bytes())
+}
+
+func (c *Context) IndentedJSON(code int, obj interface{}) {
+	c.Render(code, indentedJSONRender{}, obj)
+}
+
+// SecureJSON prefixes json arrays with "while(1);" by default
+func (c *Context) SecureJSON(code int, obj interface{}) {
+	c.Render(code, secureJSONRender{prefix: c.engine.secureJSONPrefix}, obj)
+}
+
+func (c *Context) AsciiJSON(code int, obj interface{}) {
+	c.Render(code, asciiJSONRender{}, obj)
+}
+
+// JSONP wraps the json in the function named by the callback query,
+// it is plain JSON when there is no callback and 400 when the callback
+// isn't a possibly dotted identifier
+func (c *Context) JSONP(code int, obj interface{}) {
+	callback := c.Query("callback")
+	if callback == "" {
+		c.JSON(code, obj)
+		return
+	}
+	if !jsonpCallback.MatchString(callback) {
+		c.Fail(http.StatusBadRequest, "invalid jsonp callback")
+		return
+	}
+	c.Render(code, jsonpRender{callback: callback}, obj)
+}
+
+func (c *Context) XML(code int, obj interface{}) {
+	c.Render(code, xmlRender{}, obj)
+}
+
+func (c *Context) YAML(code int, obj interface{}) {
+	c.Render(code, yamlRender{}, obj)
+}
+
+// Negotiate renders obj in the format the Accept header prefers among
+// offers, e.g. "application/json", "application/xml". Without offers
+// every registered renderer is offered. It fails with 406 when the
+// client accepts none of them
+func (c *Context) Negotiate(code int, obj interface{}, offers ...string) {
+	if len(offers) == 0 {
+		for _, r := range c.engine.renderers {
+			offers = append(offers, r.ContentType())
+		}
+	}
+	// caches keep one response per Accept header
+	c.Writer.Header().Add("Vary", "Accept")
+	offer := negotiate(c.Req.Header.Get("Accept"), offers)
+	if offer == "" {
+		c.Fail(http.StatusNotAcceptable, "Not Acceptable")
+		return
+	}
+	r := c.engine.renderer(mediaType(offer))
+	if r == nil {
+		c.Fail(http.StatusInternalServerError, fmt.Sprintf("no renderer registered for %s", offer))
+		return
+	}
+	c.Render(code, r, obj)
+}
@@ -0,0 +1,134 @@
+package goo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type todo struct {
+	ID    int    `json:"id" yaml:"id"`
+	Title string `json:"title" yaml:"title"`
+	Done  bool   `json:"done" yaml:"done,omitempty"`
+}
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{"application/json", "application/xml", "application/yaml"}
+	tests := map[string]string{
+		"":                                  "application/json",
+		"application/xml":                   "application/xml",
+		"text/html, application/*;q=0.8":    "application/json",
+		"application/json;q=0.5, */*;q=0.9": "application/xml",
+		"application/yaml, */*;q=0":         "application/yaml",
+		"text/html":                         "",
+	}
+	for accept, want := range tests {
+		if got := negotiate(accept, offers); got != want {
+			t.Errorf("negotiate(%q) = %q, want %q", accept, got, want)
+		}
+	}
+}
+
+func TestRenderFormats(t *testing.T) {
+	r := New()
+	todos := []todo{{1, "learn goo", true}, {2, "日本", false}}
+	r.GET("/todos", func(c *Context) {
+		c.Negotiate(http.StatusOK, todos)
+	})
+	r.GET("/secure", func(c *Context) {
+		c.SecureJSON(http.StatusOK, todos[:1])
+	})
+	r.GET("/ascii", func(c *Context) {
+		c.AsciiJSON(http.StatusOK, H{"title": "日本"})
+	})
+	r.GET("/jsonp", func(c *Context) {
+		c.JSONP(http.StatusOK, H{"id": 1})
+	})
+
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/todos", "application/yaml")
+	want := "- id: 1\n  title: learn goo\n  done: true\n- id: 2\n  title: \"日本\"\n"
+	if w.Header().Get("Content-Type") != "application/yaml" || w.Body.String() != want {
+		t.Fatalf("unexpected yaml %q", w.Body.String())
+	}
+	w = get("/todos", "application/xml")
+	if !strings.HasPrefix(w.Body.String(), "<todo><ID>1</ID>") {
+		t.Fatalf("unexpected xml %q", w.Body.String())
+	}
+	if w = get("/todos", "text/csv"); w.Code != http.StatusNotAcceptable {
+		t.Fatalf("unsupported Accept should be 406, got %d", w.Code)
+	}
+	if w = get("/secure", ""); w.Body.String() != `while(1);[{"id":1,"title":"learn goo","done":true}]` {
+		t.Fatalf("unexpected secure json %q", w.Body.String())
+	}
+	if w = get("/ascii", ""); w.Body.String() != `{"title":"\u65e5\u672c"}` {
+		t.Fatalf("unexpected ascii json %q", w.Body.String())
+	}
+	if w = get("/jsonp?callback=show", ""); w.Body.String() != `show({"id":1});` {
+		t.Fatalf("unexpected jsonp %q", w.Body.String())
+	}
+	if w = get("/jsonp?callback=app.todos.$show_1", ""); w.Body.String() != `app.todos.$show_1({"id":1});` {
+		t.Fatalf("unexpected jsonp %q", w.Body.String())
+	}
+	for _, callback := range []string{"alert(document.cookie)//", "a..b", "1a", "a.", "a%3Bb"} {
+		if w = get("/jsonp?callback="+callback, ""); w.Code != http.StatusBadRequest {
+			t.Fatalf("callback %q should be rejected, got %d %q", callback, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestYAMLScalars(t *testing.T) {
+	var buf strings.Builder
+	err := encodeYAML(&buf, H{
+		"hex": "0x1F", "octal": "0o17", "inf": ".inf", "nan": ".NaN", "date": "2001-12-14",
+		"float32": float32(0.1), "plain": "learn goo",
+	})
+	want := "date: \"2001-12-14\"\nfloat32: 0.1\nhex: \"0x1F\"\n\"inf\": \".inf\"\n\"nan\": \".NaN\"\noctal: \"0o17\"\nplain: learn goo\n"
+	if err != nil || buf.String() != want {
+		t.Fatalf("unexpected yaml %q %v", buf.String(), err)
+	}
+
+	cyclic := H{"name": "root"}
+	cyclic["self"] = cyclic
+	if err := encodeYAML(&buf, cyclic); err == nil {
+		t.Fatalf("cycles should fail")
+	}
+	type node struct {
+		Next *node
+	}
+	loop := &node{}
+	loop.Next = loop
+	if err := encodeYAML(&buf, loop); err == nil {
+		t.Fatalf("cycles should fail")
+	}
+	shared := []int{1}
+	buf.Reset()
+	if err := encodeYAML(&buf, H{"a": shared, "b": shared}); err != nil || buf.String() != "a:\n  - 1\nb:\n  - 1\n" {
+		t.Fatalf("unexpected yaml %q %v", buf.String(), err)
+	}
+}
+
+func TestXMLMaps(t *testing.T) {
+	var buf strings.Builder
+	err := xmlRender{}.Render(&buf, H{
+		"todo": H{"title": "learn goo", "tags": []interface{}{"go", H{"name": "web"}}},
+		"list": []H{{"id": 1}},
+	})
+	want := "<map><list><id>1</id></list><todo><tags>go</tags><tags><name>web</name></tags><title>learn goo</title></todo></map>"
+	if err != nil || buf.String() != want {
+		t.Fatalf("unexpected xml %q %v", buf.String(), err)
+	}
+	for _, key := range []string{"", "1st", "a b", "<x>", "xmlns"} {
+		if err := (xmlRender{}).Render(&buf, H{"todo": map[string]interface{}{key: 1}}); err == nil {
+			t.Fatalf("key %q should be rejected", key)
+		}
+	}
+}
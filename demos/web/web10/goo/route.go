@@ -0,0 +1,261 @@
+package goo
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+)
+
+// Route is a registered route, name it to build its URL with Engine.URL
+type Route struct {
+	Method  string
+	Pattern string
+	Host    string // host pattern, "" for the default host
+	Name    string
+	handler interface{} // HandlerFunc or ErrHandlerFunc, named by Routes
+	engine  *Engine
+	apiDoc  *routeDoc // see Route.Summary
+}
+
+// Named names the route, it panics when the name is already taken
+// so duplicates are found at startup
+func (r *Route) Named(name string) *Route {
+	engine := r.engine
+	if old, ok := engine.namedRoutes[name]; ok {
+		panic(fmt.Sprintf("goo: route name %q is used by %s %s and %s %s",
+			name, old.Method, old.Pattern, r.Method, r.Pattern))
+	}
+	if engine.namedRoutes == nil {
+		engine.namedRoutes = make(map[string]*Route)
+	}
+	r.Name = name
+	engine.namedRoutes[name] = r
+	return r
+}
+
+// URL builds the path of the named route, params fill its :param and
+// *wildcard segments in order. It is available to templates as
+//
+//	<a href="{{url "todo" .ID}}">
+func (engine *Engine) URL(name string, params ...interface{}) (string, error) {
+	r, ok := engine.namedRoutes[name]
+	if !ok {
+		return "", fmt.Errorf("goo: no route named %q", name)
+	}
+	return r.URL(params...)
+}
+
+// URL builds the path of the route from params
+func (r *Route) URL(params ...interface{}) (string, error) {
+	// the trie parts of the pattern with its optional param, whose nodes
+	// hold the compiled constraints
+	expanded := expandOptional(r.Pattern)
+	trieParts := parsePattern(expanded[len(expanded)-1])
+	var n *node
+	if r.engine != nil {
+		n = r.engine.routerFor(r.Host).roots[r.Method]
+	}
+
+	var b strings.Builder
+	i := 0
+	for j, part := range parsePattern(r.Pattern) {
+		if n != nil {
+			n = n.child(trieParts[j])
+		}
+		optional := strings.HasSuffix(part, "?") && part[0] == ':'
+		if optional && i >= len(params) {
+			break
+		}
+		if optional && fmt.Sprint(params[i]) == "" {
+			i++
+			break
+		}
+		b.WriteString("/")
+		if part[0] != ':' && part[0] != '*' {
+			b.WriteString(part)
+			continue
+		}
+		if i >= len(params) {
+			return "", fmt.Errorf("goo: route %q is missing parameter %s", r.Name, part)
+		}
+		value := fmt.Sprint(params[i])
+		i++
+		if part[0] == '*' {
+			// wildcards span segments, escape each of them
+			segments := strings.Split(strings.TrimPrefix(value, "/"), "/")
+			for j, segment := range segments {
+				segments[j] = url.PathEscape(segment)
+			}
+			b.WriteString(strings.Join(segments, "/"))
+		} else {
+			if value == "" {
+				return "", fmt.Errorf("goo: route %q has an empty parameter %s", r.Name, part)
+			}
+			if n != nil && n.match != nil && !n.match(value) {
+				return "", fmt.Errorf("goo: route %q parameter %s doesn't match %q", r.Name, part, value)
+			}
+			b.WriteString(url.PathEscape(value))
+		}
+	}
+	if i < len(params) {
+		return "", fmt.Errorf("goo: route %q takes %d parameters, got %d", r.Name, i, len(params))
+	}
+	if b.Len() == 0 {
+		return "/", nil
+	}
+	if strings.HasSuffix(r.Pattern, "/") {
+		b.WriteString("/")
+	}
+	return b.String(), nil
+}
+
+// paramCount returns how many params URL needs at least and at most
+func (r *Route) paramCount() (min int, max int) {
+	for _, part := range parsePattern(r.Pattern) {
+		if part[0] == ':' || part[0] == '*' {
+			max++
+			if !strings.HasSuffix(part, "?") {
+				min++
+			}
+		}
+	}
+	return min, max
+}
+
+// CheckURLs checks the url calls of the loaded templates against the
+// named routes, so a missing route or param fails at startup instead of
+// when a page renders. Run and the other Run methods call it. Calls
+// whose route name isn't a string constant are only checked by URL
+func (engine *Engine) CheckURLs() error {
+	t, ok := engine.htmlRender.(*HTMLTemplates)
+	if !ok {
+		return nil
+	}
+	for _, call := range t.urlCalls() {
+		r, ok := engine.namedRoutes[call.route]
+		if !ok {
+			return fmt.Errorf("goo: template %s: no route named %q", call.template, call.route)
+		}
+		if min, max := r.paramCount(); call.params < min || call.params > max {
+			return fmt.Errorf("goo: template %s: route %q takes %d to %d parameters, got %d",
+				call.template, call.route, min, max, call.params)
+		}
+	}
+	return nil
+}
+
+// RouteInfo describes a registered route, see Engine.Routes
+type RouteInfo struct {
+	Method      string   `json:"method"`
+	Pattern     string   `json:"pattern"`
+	Host        string   `json:"host,omitempty"`
+	Name        string   `json:"name,omitempty"`
+	Handler     string   `json:"handler"`
+	Middlewares []string `json:"middlewares"`
+}
+
+func funcName(f interface{}) string {
+	v := reflect.ValueOf(f)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return ""
+	}
+	return runtime.FuncForPC(v.Pointer()).Name()
+}
+
+// Routes returns every registered route in registration order, with
+// the middlewares of the groups it belongs to
+func (engine *Engine) Routes() []RouteInfo {
+	routes := make([]RouteInfo, 0, len(engine.routes))
+	for _, r := range engine.routes {
+		middlewares := make([]string, 0)
+		for _, group := range engine.groups {
+			if (group.host == "" || group.host == r.Host) && strings.HasPrefix(r.Pattern, group.prefix) {
+				for _, m := range group.middlewares {
+					middlewares = append(middlewares, funcName(m))
+				}
+			}
+		}
+		routes = append(routes, RouteInfo{
+			Method:      r.Method,
+			Pattern:     r.Pattern,
+			Host:        r.Host,
+			Name:        r.Name,
+			Handler:     funcName(r.handler),
+			Middlewares: middlewares,
+		})
+	}
+	return routes
+}
+
+// RouteTable serves the route table as json, or as text when asked
+// with ?format=text or Accept: text/plain
+func RouteTable() HandlerFunc {
+	return func(c *Context) {
+		routes := c.engine.Routes()
+		format := c.Query("format")
+		if format == "" && negotiate(c.Req.Header.Get("Accept"), []string{"application/json", "text/plain"}) == "text/plain" {
+			format = "text"
+		}
+		if format != "text" {
+			c.JSON(http.StatusOK, routes)
+			return
+		}
+
+		c.SetHeader("Content-Type", "text/plain")
+		c.Status(http.StatusOK)
+		w := tabwriter.NewWriter(c.Writer, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "METHOD\tPATTERN\tNAME\tHANDLER\tMIDDLEWARES")
+		for _, r := range routes {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Method, r.Pattern, r.Name, r.Handler, strings.Join(r.Middlewares, ", "))
+		}
+		w.Flush()
+	}
+}
+
+// warnShadowedRoutes logs routes of method that can't be reached
+// because another route matches their pattern first
+func (engine *Engine) warnShadowedRoutes(method string, host string) {
+	root := engine.routerFor(host).roots[method]
+	for _, r := range engine.routes {
+		if r.Method != method || r.Host != host || engine.shadowWarned[r] {
+			continue
+		}
+		winner := ""
+		for _, pattern := range expandOptional(r.Pattern) {
+			n := root.search(parsePattern(pattern), 0)
+			if n == nil || n.pattern != r.Pattern {
+				winner = "a later registration"
+				if n != nil {
+					winner = n.pattern
+				}
+				break
+			}
+		}
+		if winner == "" && engine.lastRoute(method, host, r.Pattern) == r {
+			continue
+		}
+		if winner == "" {
+			winner = "a later registration"
+		}
+		log.Printf("[WARNING] Route %4s - %s is shadowed by %s", method, r.Pattern, winner)
+		if engine.shadowWarned == nil {
+			engine.shadowWarned = make(map[*Route]bool)
+		}
+		engine.shadowWarned[r] = true
+	}
+}
+
+// lastRoute returns the route whose handler is registered for pattern
+func (engine *Engine) lastRoute(method string, host string, pattern string) *Route {
+	for i := len(engine.routes) - 1; i >= 0; i-- {
+		if r := engine.routes[i]; r.Method == method && r.Host == host && r.Pattern == pattern {
+			return r
+		}
+	}
+	return nil
+}
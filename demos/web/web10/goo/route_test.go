@@ -0,0 +1,162 @@
+package goo
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestURL(t *testing.T) {
+	r := New()
+	r.GET("/", nil).Named("home")
+	r.GET("/todo/:id/:action", nil).Named("todo")
+	r.Static("/assets", ".").Named("assets")
+
+	tests := []struct {
+		name   string
+		params []interface{}
+		want   string
+	}{
+		{"home", nil, "/"},
+		{"todo", []interface{}{42, "edit me"}, "/todo/42/edit%20me"},
+		{"assets", []interface{}{"css/goo.css"}, "/assets/css/goo.css"},
+	}
+	for _, test := range tests {
+		if got, err := r.URL(test.name, test.params...); err != nil || got != test.want {
+			t.Errorf("URL(%q) = %q, %v, want %q", test.name, got, err, test.want)
+		}
+	}
+
+	if _, err := r.URL("todo", 42); err == nil {
+		t.Error("missing parameter should fail")
+	}
+	if _, err := r.URL("home", 1); err == nil {
+		t.Error("extra parameter should fail")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("duplicate route name should panic")
+		}
+	}()
+	r.GET("/other", nil).Named("home")
+}
+
+func TestURLInTemplate(t *testing.T) {
+	r := New()
+	r.LoadHTMLFS(fstest.MapFS{"link.tmpl": {Data: []byte(`<a href="{{url "todo" .}}">`)}}, "*.tmpl")
+	r.GET("/todo/:id", func(c *Context) {
+		c.HTML(http.StatusOK, "link.tmpl", 7)
+	}).Named("todo")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/todo/1", nil))
+	if w.Body.String() != `<a href="/todo/7">` {
+		t.Fatalf("unexpected link %q", w.Body.String())
+	}
+}
+
+func getTodo(c *Context) {}
+
+func TestRoutes(t *testing.T) {
+	r := New()
+	r.Use(Logger())
+	v1 := r.Group("/v1")
+	v1.Use(Recovery())
+	v1.GET("/todo/:id", getTodo).Named("todo")
+	r.GET("/debug/routes", RouteTable())
+
+	routes := r.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("expected 2 routes, got %d", len(routes))
+	}
+	todo := routes[0]
+	if todo.Pattern != "/v1/todo/:id" || todo.Name != "todo" || todo.Handler != "goo.getTodo" ||
+		len(todo.Middlewares) != 2 || !strings.HasPrefix(todo.Middlewares[1], "goo.Recovery") {
+		t.Fatalf("unexpected route info %+v", todo)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/debug/routes?format=text", nil))
+	if !strings.Contains(w.Body.String(), "GET     /v1/todo/:id") {
+		t.Fatalf("unexpected route table %q", w.Body.String())
+	}
+}
+
+func TestShadowedRouteWarning(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	r := New()
+	r.GET("/hello/:name", nil)
+	r.GET("/assets/*filepath", nil)
+	r.GET("/hello/:id", nil)
+	r.GET("/assets/logo.png", nil)
+
+	for _, want := range []string{
+		"/hello/:name is shadowed by /hello/:id",
+		"/assets/*filepath is shadowed by /assets/logo.png",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected warning %q in %q", want, buf.String())
+		}
+	}
+}
+
+func TestURLTrailingSlashAndConstraints(t *testing.T) {
+	r := New()
+	compiled := 0
+	r.RegisterConstraint("even", func(s string) bool {
+		compiled++
+		return strings.Trim(s, "02468") == ""
+	})
+	r.GET("/hello/", nil).Named("hello")
+	r.GET("/page/:n<even>/", nil).Named("page")
+
+	if got, err := r.URL("hello"); err != nil || got != "/hello/" {
+		t.Fatalf("unexpected url %q %v", got, err)
+	}
+	if got, err := r.URL("page", 4); err != nil || got != "/page/4/" {
+		t.Fatalf("unexpected url %q %v", got, err)
+	}
+	if _, err := r.URL("page", 3); err == nil {
+		t.Fatalf("odd page should fail")
+	}
+	if compiled != 2 {
+		t.Fatalf("the constraint of the route should be used, called %d times", compiled)
+	}
+}
+
+func TestCheckURLs(t *testing.T) {
+	load := func(tmpl string) *Engine {
+		r := New()
+		r.LoadHTMLFS(fstest.MapFS{"link.tmpl": {Data: []byte(tmpl)}}, "*.tmpl")
+		r.GET("/todo/:id/:action?", nil).Named("todo")
+		return r
+	}
+	for _, tmpl := range []string{
+		`{{url "todo" 1}}`,
+		`{{url "todo" 1 "edit"}}`,
+		`{{if .}}{{.ID | url "todo"}}{{end}}`,
+		`{{range .}}{{template "x" (url "todo" .ID)}}{{end}}{{define "x"}}{{.}}{{end}}`,
+	} {
+		if err := load(tmpl).CheckURLs(); err != nil {
+			t.Fatalf("%s: unexpected error %v", tmpl, err)
+		}
+	}
+	for _, tmpl := range []string{
+		`{{url "todo"}}`,
+		`{{url "todo" 1 "edit" 2}}`,
+		`{{with .}}{{url "missing" .}}{{end}}`,
+	} {
+		if err := load(tmpl).CheckURLs(); err == nil {
+			t.Fatalf("%s should fail", tmpl)
+		}
+	}
+}
@@ -2,18 +2,21 @@ package goo
 
 import (
 	"net/http"
+	"path"
 	"strings"
 )
 
 type router struct {
-	roots    map[string]*node
-	handlers map[string]HandlerFunc
+	roots       map[string]*node
+	handlers    map[string]HandlerFunc
+	constraints map[string]func(string) bool // shared by the engine's routers
 }
 
 func newRouter() *router {
 	return &router{
-		roots:    make(map[string]*node),
-		handlers: make(map[string]HandlerFunc),
+		roots:       make(map[string]*node),
+		handlers:    make(map[string]HandlerFunc),
+		constraints: defaultConstraints(),
 	}
 }
 
@@ -33,15 +36,37 @@ func parsePattern(pattern string) []string {
 	return parts
 }
 
-func (r *router) addRoute(method string, pattern string, handler HandlerFunc) {
-	parts := parsePattern(pattern)
+// expandOptional returns the patterns matched by a pattern ending with
+// an optional param, /files/:name? matches /files and /files/:name
+func expandOptional(pattern string) []string {
+	i := strings.LastIndexByte(pattern, '/')
+	if !strings.HasSuffix(pattern, "?") || !strings.HasPrefix(pattern[i+1:], ":") {
+		return []string{pattern}
+	}
+	without := pattern[:i]
+	if without == "" {
+		without = "/"
+	}
+	return []string{without, strings.TrimSuffix(pattern, "?")}
+}
 
-	key := method + "-" + pattern
+func (r *router) addRoute(method string, pattern string, handler HandlerFunc) {
 	_, ok := r.roots[method]
 	if !ok {
 		r.roots[method] = &node{}
 	}
-	r.roots[method].insert(pattern, parts, 0)
+	for _, expanded := range expandOptional(pattern) {
+		parts := parsePattern(expanded)
+		matchers := make(map[string]func(string) bool)
+		for _, part := range parts {
+			if hasConstraint(part) {
+				matchers[part] = r.compileConstraint(part)
+			}
+		}
+		r.roots[method].insert(pattern, parts, 0, matchers)
+	}
+
+	key := method + "-" + pattern
 	r.handlers[key] = handler
 }
 
@@ -59,11 +84,16 @@ func (r *router) getRoute(method string, path string) (*node, map[string]string)
 	if n != nil {
 		parts := parsePattern(n.pattern)
 		for index, part := range parts {
+			if index >= len(searchParts) {
+				break // optional param left out
+			}
 			if part[0] == ':' {
-				params[part[1:]] = searchParts[index]
+				name, _ := splitParam(part)
+				params[name] = searchParts[index]
 			}
 			if part[0] == '*' && len(part) > 1 {
-				params[part[1:]] = strings.Join(searchParts[index:], "/")
+				name, _ := splitParam(part)
+				params[name] = strings.Join(searchParts[index:], "/")
 				break
 			}
 		}
@@ -83,12 +113,109 @@ func (r *router) getRoutes(method string) []*node {
 	return nodes
 }
 
+// redirectCode is 301 for GET and HEAD, other methods get 308
+// so clients repeat them with the same method and body
+func redirectCode(method string) int {
+	if method == http.MethodGet || method == http.MethodHead {
+		return http.StatusMovedPermanently
+	}
+	return http.StatusPermanentRedirect
+}
+
+// cleanPath removes empty, . and .. segments but keeps a trailing slash
+func cleanPath(p string) string {
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// hasDotSegment reports whether p has . or .. segments, clients
+// resolve them in a Location so the redirect would lead elsewhere
+func hasDotSegment(p string) bool {
+	for _, segment := range strings.Split(p, "/") {
+		if segment == "." || segment == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// fixTrailingSlash returns p with a trailing slash exactly when the
+// matched pattern has one, wildcards match either way
+func fixTrailingSlash(pattern string, p string) string {
+	parts := parsePattern(pattern)
+	if p == "/" || len(parts) > 0 && parts[len(parts)-1][0] == '*' {
+		return p
+	}
+	if strings.HasSuffix(pattern, "/") {
+		return strings.TrimRight(p, "/") + "/"
+	}
+	return strings.TrimRight(p, "/")
+}
+
+// redirectPath returns where the engine's path options redirect the
+// request to, or "" when the request is routed as it is
+func (r *router) redirectPath(c *Context, n *node) string {
+	engine := c.engine
+	if engine == nil {
+		return ""
+	}
+	if engine.RemoveExtraSlash && strings.Contains(c.Path, "//") {
+		return cleanPath(c.Path)
+	}
+	if n != nil {
+		if engine.RedirectTrailingSlash && !hasDotSegment(c.Path) {
+			// //evil.com/ would become //evil.com, another host
+			fixed := "/" + strings.TrimLeft(fixTrailingSlash(n.pattern, c.Path), "/\\")
+			if target, _ := r.getRoute(c.Method, fixed); fixed != c.Path && target == n {
+				return fixed
+			}
+		}
+		return ""
+	}
+	if engine.RedirectFixedPath {
+		cleaned := cleanPath(c.Path)
+		if root, ok := r.roots[c.Method]; ok {
+			if parts, ok := root.searchFold(parsePattern(cleaned), 0); ok {
+				fixed := "/" + strings.Join(parts, "/")
+				if strings.HasSuffix(cleaned, "/") && fixed != "/" {
+					fixed += "/"
+				}
+				if fixed != c.Path {
+					return fixed
+				}
+			}
+		}
+	}
+	return ""
+}
+
 func (r *router) handle(c *Context) {
 	n, params := r.getRoute(c.Method, c.Path)
 
-	if n != nil {
+	if location := r.redirectPath(c, n); location != "" {
+		// browsers read //host and /\host as another host
+		location = "/" + strings.TrimLeft(location, "/\\")
+		if c.Req.URL.RawQuery != "" {
+			location += "?" + c.Req.URL.RawQuery
+		}
+		c.handlers = append(c.handlers, func(c *Context) {
+			// http.Redirect would clean the path again
+			c.SetHeader("Location", location)
+			c.Status(redirectCode(c.Method))
+		})
+	} else if n != nil {
 		key := c.Method + "-" + n.pattern
-		c.Params = params
+		c.pattern = n.pattern
+		if c.Params == nil {
+			c.Params = params
+		} else {
+			for k, v := range params {
+				c.Params[k] = v
+			}
+		}
 		c.handlers = append(c.handlers, r.handlers[key])
 	} else {
 		c.handlers = append(c.handlers, func(c *Context) {
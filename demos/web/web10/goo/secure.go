@@ -0,0 +1,119 @@
+package goo
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+// SecureConfig sets the security headers written by Secure,
+// empty values leave the header out
+type SecureConfig struct {
+	// HSTSMaxAge in seconds is sent in Strict-Transport-Security over
+	// https, including requests forwarded with X-Forwarded-Proto: https
+	HSTSMaxAge            int
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	FrameOptions          string // X-Frame-Options
+	ContentTypeOptions    string // X-Content-Type-Options
+	ReferrerPolicy        string
+	PermissionsPolicy     string
+	// ContentSecurityPolicy may contain {nonce}, which is replaced with
+	// the nonce of the request, see Context.CSPNonce
+	ContentSecurityPolicy string
+	// CSPReportOnly sends the policy as Content-Security-Policy-Report-Only
+	CSPReportOnly bool
+}
+
+// DefaultSecureConfig is a strict configuration for html pages,
+// inline scripts and styles need the nonce of the request
+func DefaultSecureConfig() SecureConfig {
+	return SecureConfig{
+		HSTSMaxAge:            365 * 24 * 60 * 60,
+		HSTSIncludeSubdomains: true,
+		FrameOptions:          "DENY",
+		ContentTypeOptions:    "nosniff",
+		ReferrerPolicy:        "strict-origin-when-cross-origin",
+		PermissionsPolicy:     "camera=(), microphone=(), geolocation=()",
+		ContentSecurityPolicy: "default-src 'self'; script-src 'self' 'nonce-{nonce}'; " +
+			"style-src 'self' 'nonce-{nonce}'; object-src 'none'; base-uri 'self'; frame-ancestors 'none'",
+	}
+}
+
+// Secure sets the security headers of config. Groups override the
+// headers of their parents by using Secure again, the innermost wins
+func Secure(config SecureConfig) HandlerFunc {
+	var hsts string
+	if config.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(config.HSTSMaxAge)
+		if config.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if config.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+	cspHeader, otherCSPHeader := "Content-Security-Policy", "Content-Security-Policy-Report-Only"
+	if config.CSPReportOnly {
+		cspHeader, otherCSPHeader = otherCSPHeader, cspHeader
+	}
+
+	return func(c *Context) {
+		header := c.Writer.Header()
+		set := func(key, value string) {
+			if value == "" {
+				header.Del(key)
+			} else {
+				header.Set(key, value)
+			}
+		}
+		if c.Req.TLS != nil || c.Req.Header.Get("X-Forwarded-Proto") == "https" {
+			set("Strict-Transport-Security", hsts)
+		}
+		set("X-Frame-Options", config.FrameOptions)
+		set("X-Content-Type-Options", config.ContentTypeOptions)
+		set("Referrer-Policy", config.ReferrerPolicy)
+		set("Permissions-Policy", config.PermissionsPolicy)
+
+		csp := config.ContentSecurityPolicy
+		if strings.Contains(csp, "{nonce}") {
+			csp = strings.ReplaceAll(csp, "{nonce}", c.CSPNonce())
+		}
+		header.Del(otherCSPHeader)
+		set(cspHeader, csp)
+		c.Next()
+	}
+}
+
+// CSPNonce returns the random nonce of the request for inline
+// <script nonce="..."> tags. Context.HTML passes it to templates
+// rendering an H or nil as .cspNonce
+func (c *Context) CSPNonce() string {
+	if c.cspNonce == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			panic(err)
+		}
+		c.cspNonce = base64.RawURLEncoding.EncodeToString(b) // nothing templates escape
+	}
+	return c.cspNonce
+}
+
+// withNonce adds cspNonce to a copy of template data of type H
+func withNonce(data interface{}, nonce string) interface{} {
+	var copied H
+	switch data := data.(type) {
+	case nil:
+		copied = H{}
+	case H:
+		copied = make(H, len(data)+1)
+		for k, v := range data {
+			copied[k] = v
+		}
+	default:
+		return data
+	}
+	copied["cspNonce"] = nonce
+	return copied
+}
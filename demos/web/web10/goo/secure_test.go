@@ -0,0 +1,78 @@
+package goo
+
+import (
+	"crypto/tls"
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type stringRender struct {
+	t *template.Template
+}
+
+func (r stringRender) Render(w io.Writer, name string, data interface{}) error {
+	return r.t.ExecuteTemplate(w, name, data)
+}
+
+func TestSecure(t *testing.T) {
+	r := New()
+	r.Use(Secure(DefaultSecureConfig()))
+	r.SetHTMLRender(stringRender{template.Must(template.New("page").Parse(
+		`<script nonce="{{.cspNonce}}">hi({{.name}})</script>`))})
+	r.GET("/page", func(c *Context) {
+		c.HTML(http.StatusOK, "page", H{"name": "goo"})
+	})
+	embed := r.Group("/embed")
+	config := DefaultSecureConfig()
+	config.FrameOptions = ""
+	config.ContentSecurityPolicy = "frame-ancestors https://example.com"
+	config.CSPReportOnly = true
+	embed.Use(Secure(config))
+	embed.GET("/widget", func(c *Context) {
+		c.String(http.StatusOK, "widget")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/page", nil))
+	h := w.Header()
+	if h.Get("X-Frame-Options") != "DENY" || h.Get("X-Content-Type-Options") != "nosniff" ||
+		h.Get("Referrer-Policy") == "" || h.Get("Permissions-Policy") == "" {
+		t.Fatalf("unexpected headers %v", h)
+	}
+	if h.Get("Strict-Transport-Security") != "" {
+		t.Fatal("HSTS shouldn't be sent over http")
+	}
+	csp := h.Get("Content-Security-Policy")
+	i := strings.Index(csp, "'nonce-")
+	if i < 0 {
+		t.Fatalf("unexpected csp %q", csp)
+	}
+	nonce := csp[i+len("'nonce-"):]
+	nonce = nonce[:strings.IndexByte(nonce, '\'')]
+	if !strings.Contains(w.Body.String(), `nonce="`+nonce+`"`) {
+		t.Fatalf("unexpected body %q for nonce %q", w.Body.String(), nonce)
+	}
+
+	w2 := httptest.NewRecorder()
+	r.ServeHTTP(w2, httptest.NewRequest("GET", "/page", nil))
+	if w2.Header().Get("Content-Security-Policy") == csp {
+		t.Fatal("nonces should differ per request")
+	}
+
+	req := httptest.NewRequest("GET", "/embed/widget", nil)
+	req.TLS = &tls.ConnectionState{}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	h = w.Header()
+	if h.Get("X-Frame-Options") != "" || h.Get("Content-Security-Policy") != "" ||
+		h.Get("Content-Security-Policy-Report-Only") != "frame-ancestors https://example.com" {
+		t.Fatalf("unexpected headers %v", h)
+	}
+	if h.Get("Strict-Transport-Security") != "max-age=31536000; includeSubDomains" {
+		t.Fatalf("unexpected HSTS %q", h.Get("Strict-Transport-Security"))
+	}
+}
@@ -0,0 +1,133 @@
+package goo
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TLSConfig returns a tls config for certs that negotiates HTTP/2
+// with ALPN and falls back to HTTP/1.1. A QUIC server for HTTP/3 can
+// reuse it after adding "h3" to NextProtos
+func TLSConfig(certs ...tls.Certificate) *tls.Config {
+	return &tls.Config{
+		Certificates: certs,
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+}
+
+// SelfSignedCert generates a certificate for local development, valid
+// for hosts (localhost, 127.0.0.1 and ::1 by default) for 30 days
+func SelfSignedCert(hosts ...string) (tls.Certificate, error) {
+	if len(hosts) == 0 {
+		hosts = []string{"localhost", "127.0.0.1", "::1"}
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"goo development"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(30 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+// RunTLS serves HTTP/2 and HTTP/1.1 over tls
+func (engine *Engine) RunTLS(addr string, certFile string, keyFile string) error {
+	if err := engine.CheckURLs(); err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Addr: addr, Handler: engine, TLSConfig: TLSConfig(cert)}
+	return server.ListenAndServeTLS("", "")
+}
+
+// RunDevTLS serves over tls with a fresh self-signed certificate,
+// browsers warn about it so it is only meant for local development
+func (engine *Engine) RunDevTLS(addr string) error {
+	if err := engine.CheckURLs(); err != nil {
+		return err
+	}
+	cert, err := SelfSignedCert()
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Addr: addr, Handler: engine, TLSConfig: TLSConfig(cert)}
+	return server.ListenAndServeTLS("", "")
+}
+
+// Push starts an HTTP/2 server push of target, it returns
+// http.ErrNotSupported when the connection or client can't push
+func (c *Context) Push(target string, opts *http.PushOptions) error {
+	w := c.Writer
+	for {
+		if pusher, ok := w.(http.Pusher); ok {
+			return pusher.Push(target, opts)
+		}
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return http.ErrNotSupported
+		}
+		w = unwrapper.Unwrap()
+	}
+}
+
+// Preload pushes targets before the handlers behind it run, clients
+// that can't receive pushes get Link preload headers instead. Requests
+// matching no route, like 404s, aren't pushed anything
+func Preload(targets ...string) HandlerFunc {
+	return func(c *Context) {
+		if c.pattern == "" {
+			c.Next()
+			return
+		}
+		var links []string
+		for _, target := range targets {
+			if err := c.Push(target, nil); err != nil {
+				links = append(links, "<"+target+">; rel=preload")
+			}
+		}
+		if len(links) > 0 {
+			c.Writer.Header().Add("Link", strings.Join(links, ", "))
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,121 @@
+//go:build go1.24
+
+package goo
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newProtoEngine(pushed chan<- error) *Engine {
+	r := New()
+	r.Use(Preload("/assets/app.css"))
+	r.GET("/proto", func(c *Context) {
+		pushed <- c.Push("/assets/app.js", nil)
+		c.String(http.StatusOK, "%s", c.Req.Proto)
+	})
+	return r
+}
+
+func serve(t *testing.T, server *http.Server, tlsConfig *tls.Config) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+	go server.Serve(ln)
+	t.Cleanup(func() { server.Close() })
+	return ln.Addr().String()
+}
+
+func getProto(t *testing.T, client *http.Client, url string) *http.Response {
+	res, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || string(body) != res.Proto {
+		t.Fatalf("unexpected response %d %q over %s", res.StatusCode, body, res.Proto)
+	}
+	return res
+}
+
+func TestH2C(t *testing.T) {
+	pushed := make(chan error, 1)
+	server, err := newProtoEngine(pushed).H2CServer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := serve(t, server, nil)
+
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+	res := getProto(t, client, "http://"+addr+"/proto")
+	if res.ProtoMajor != 2 {
+		t.Fatalf("unexpected protocol %s", res.Proto)
+	}
+	// Go clients disable server push
+	if err := <-pushed; err == nil {
+		t.Fatal("push should fail without client support")
+	}
+	if res.Header.Get("Link") != "</assets/app.css>; rel=preload" {
+		t.Fatalf("unexpected link %q", res.Header.Get("Link"))
+	}
+
+	// HTTP/1.1 clients are still served
+	res = getProto(t, http.DefaultClient, "http://"+addr+"/proto")
+	if res.ProtoMajor != 1 {
+		t.Fatalf("unexpected protocol %s", res.Proto)
+	}
+	if err := <-pushed; err != http.ErrNotSupported {
+		t.Fatalf("unexpected push error %v", err)
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	cert, err := SelfSignedCert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pushed := make(chan error, 1)
+	r := newProtoEngine(pushed)
+	addr := serve(t, &http.Server{Handler: r}, TLSConfig(cert))
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert.Leaf)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots},
+		ForceAttemptHTTP2: true,
+	}}
+	res := getProto(t, client, "https://"+addr+"/proto")
+	<-pushed
+	if res.ProtoMajor != 2 || res.TLS.NegotiatedProtocol != "h2" {
+		t.Fatalf("unexpected protocol %s %q", res.Proto, res.TLS.NegotiatedProtocol)
+	}
+	if res = getProto(t, client, "https://localhost:"+addr[len("127.0.0.1:"):]+"/proto"); res.ProtoMajor != 2 {
+		t.Fatalf("unexpected protocol %s", res.Proto)
+	}
+	<-pushed
+}
+
+func TestPreloadMatchedRoutes(t *testing.T) {
+	r := New()
+	r.Use(Preload("/assets/app.css"))
+	r.GET("/", func(c *Context) { c.String(http.StatusOK, "home") })
+	for path, link := range map[string]string{"/": "</assets/app.css>; rel=preload", "/missing": ""} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Header().Get("Link") != link {
+			t.Fatalf("unexpected link %q for %s", w.Header().Get("Link"), path)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package goo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event is a server-sent event, see
+// https://html.spec.whatwg.org/multipage/server-sent-events.html
+type Event struct {
+	ID    string
+	Event string
+	Retry time.Duration // reconnection time sent to the client, 0 omits it
+	Data  interface{}   // string and []byte are sent as is, others as json
+}
+
+func writeEvent(w io.Writer, e Event) error {
+	var data string
+	switch v := e.Data.(type) {
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(b)
+	}
+
+	var b strings.Builder
+	if e.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", oneLine(e.ID))
+	}
+	if e.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", oneLine(e.Event))
+	}
+	if e.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %d\n", e.Retry.Milliseconds())
+	}
+	// every line ending the spec knows, a lone \r would end the line too
+	data = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(data)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func oneLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// Flush sends any buffered data to the client
+func (c *Context) Flush() {
+	if f, ok := c.Writer.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Stream calls step and flushes until step returns false or the
+// client goes away, it returns true when the client disconnected
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	done := c.Req.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		default:
+			keepOpen := step(c.Writer)
+			c.Flush()
+			if !keepOpen {
+				return false
+			}
+		}
+	}
+}
+
+// startSSE sends the event stream headers once
+func (c *Context) startSSE() {
+	header := c.Writer.Header()
+	if header.Get("Content-Type") != "text/event-stream" {
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("Connection", "keep-alive")
+		c.Status(http.StatusOK)
+	}
+}
+
+// SSE writes e to a text/event-stream response
+func (c *Context) SSE(e Event) {
+	c.startSSE()
+	if err := writeEvent(c.Writer, e); err != nil {
+		// headers are already sent, report the error as an event
+		writeEvent(c.Writer, Event{Event: "error", Data: err.Error()})
+	}
+	c.Flush()
+}
+
+// SSEvent writes a named server-sent event
+func (c *Context) SSEvent(name string, data interface{}) {
+	c.SSE(Event{Event: name, Data: data})
+}
+
+// Broker fans out every published event to all subscribers, like
+// the producer-consumer demos one goroutine owns the subscriber set
+// and everything else talks to it through channels
+type Broker struct {
+	events      chan Event
+	subscribe   chan chan Event
+	unsubscribe chan chan Event
+	quit        chan struct{}
+	closeOnce   sync.Once
+	clients     map[chan Event]bool
+}
+
+// NewBroker starts a broker, call Close to stop it
+func NewBroker() *Broker {
+	b := &Broker{
+		events:      make(chan Event),
+		subscribe:   make(chan chan Event),
+		unsubscribe: make(chan chan Event),
+		quit:        make(chan struct{}),
+		clients:     make(map[chan Event]bool),
+	}
+	go b.run()
+	return b
+}
+
+func (b *Broker) run() {
+	for {
+		select {
+		case ch := <-b.subscribe:
+			b.clients[ch] = true
+		case ch := <-b.unsubscribe:
+			if b.clients[ch] {
+				delete(b.clients, ch)
+				close(ch)
+			}
+		case e := <-b.events:
+			for ch := range b.clients {
+				select {
+				case ch <- e:
+				default:
+					// drop the event for slow subscribers instead of blocking everyone
+				}
+			}
+		case <-b.quit:
+			for ch := range b.clients {
+				close(ch)
+			}
+			b.clients = nil
+			return
+		}
+	}
+}
+
+// Publish sends e to every current subscriber
+func (b *Broker) Publish(e Event) {
+	select {
+	case b.events <- e:
+	case <-b.quit:
+	}
+}
+
+// Subscribe returns a channel receiving the published events,
+// it is closed by Unsubscribe or Close
+func (b *Broker) Subscribe() chan Event {
+	ch := make(chan Event, 16)
+	select {
+	case b.subscribe <- ch:
+	case <-b.quit:
+		close(ch)
+	}
+	return ch
+}
+
+func (b *Broker) Unsubscribe(ch chan Event) {
+	select {
+	case b.unsubscribe <- ch:
+	case <-b.quit:
+	}
+}
+
+// Close stops the broker and closes every subscriber channel,
+// calling it again does nothing
+func (b *Broker) Close() {
+	b.closeOnce.Do(func() { close(b.quit) })
+}
+
+// Handler streams the published events to the client until it disconnects
+func (b *Broker) Handler() HandlerFunc {
+	return func(c *Context) {
+		ch := b.Subscribe()
+		defer b.Unsubscribe(ch)
+		c.startSSE()
+		c.Flush()
+
+		done := c.Req.Context().Done()
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case e, ok := <-ch:
+				if !ok {
+					return false
+				}
+				c.SSE(e)
+				return true
+			case <-done:
+				return false
+			}
+		})
+	}
+}
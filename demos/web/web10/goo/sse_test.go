@@ -0,0 +1,62 @@
+package goo
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSEvent(t *testing.T) {
+	r := New()
+	r.GET("/events", func(c *Context) {
+		c.SSE(Event{ID: "1", Event: "todo", Retry: 3 * time.Second, Data: "a\r\nb\rc\nd"})
+		c.SSEvent("count", H{"n": 2})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/events", nil))
+	want := "id: 1\nevent: todo\nretry: 3000\ndata: a\ndata: b\ndata: c\ndata: d\n\n" +
+		"event: count\ndata: {\"n\":2}\n\n"
+	if w.Header().Get("Content-Type") != "text/event-stream" || w.Body.String() != want {
+		t.Fatalf("unexpected event stream %q", w.Body.String())
+	}
+}
+
+func TestBroker(t *testing.T) {
+	b := NewBroker()
+	defer b.Close()
+	r := New()
+	r.GET("/events", b.Handler())
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// the handler subscribes before it sends the headers
+	b.Publish(Event{Event: "todo", Data: "added"})
+
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 2 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	if lines[0] != "event: todo" || lines[1] != "data: added" {
+		t.Fatalf("unexpected event %q", lines)
+	}
+	// closing early and again with the deferred Close is fine
+	b.Close()
+	if _, ok := <-b.Subscribe(); ok {
+		t.Fatalf("subscriptions of a closed broker should be closed")
+	}
+}
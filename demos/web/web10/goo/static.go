@@ -0,0 +1,184 @@
+package goo
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// StaticConfig controls how a static route serves its files
+type StaticConfig struct {
+	// Index is served for directory requests, "" disables it
+	Index string
+	// Fallback is served instead of a 404 when the file doesn't exist,
+	// set it to "index.html" for single page applications
+	Fallback string
+	// Browse enables directory listing for directories without an index
+	Browse bool
+	// CacheControl maps a path prefix inside the static root to the
+	// Cache-Control header of its files, the longest prefix wins
+	CacheControl map[string]string
+	// Gzip serves the precompressed name.gz sidecar when it exists
+	// and the client accepts gzip
+	Gzip bool
+}
+
+var defaultStaticConfig = StaticConfig{Index: "index.html"}
+
+// create static handler
+func (group *RouterGroup) createStaticHandler(relativePath string, fs http.FileSystem, config StaticConfig) HandlerFunc {
+	absolutePath := path.Join(group.prefix, relativePath)
+	fileServer := http.StripPrefix(absolutePath, http.FileServer(fs))
+	etags := &staticETags{}
+	return func(c *Context) {
+		name := path.Clean("/" + c.Param("filepath"))
+		file, info, err := openStatic(fs, name, config.Index)
+		if err == errIsDir && config.Browse {
+			fileServer.ServeHTTP(c.Writer, c.Req)
+			return
+		}
+		if err != nil && config.Fallback != "" {
+			name = path.Clean("/" + config.Fallback)
+			file, info, err = openStatic(fs, name, "")
+		}
+		// Check if file exists and/or if we have permission to access it
+		if err != nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		defer file.Close()
+		serveStatic(c, fs, name, file, info, config, etags)
+	}
+}
+
+// serve static files
+func (group *RouterGroup) Static(relativePath string, root string) *Route {
+	return group.StaticWithConfig(relativePath, http.Dir(root), defaultStaticConfig)
+}
+
+// StaticFS serves static files from fsys, e.g. an embed.FS
+func (group *RouterGroup) StaticFS(relativePath string, fsys fs.FS) *Route {
+	return group.StaticWithConfig(relativePath, http.FS(fsys), defaultStaticConfig)
+}
+
+// StaticWithConfig serves static files from fs using config
+func (group *RouterGroup) StaticWithConfig(relativePath string, fs http.FileSystem, config StaticConfig) *Route {
+	handler := group.createStaticHandler(relativePath, fs, config)
+	urlPattern := path.Join(relativePath, "/*filepath")
+	// Register GET handlers
+	return group.GET(urlPattern, handler)
+}
+
+// StaticFile serves a single local file at relativePath
+func (group *RouterGroup) StaticFile(relativePath string, filePath string) *Route {
+	fs := http.Dir(filepath.Dir(filePath))
+	name := "/" + filepath.Base(filePath)
+	etags := &staticETags{}
+	return group.GET(relativePath, func(c *Context) {
+		file, info, err := openStatic(fs, name, "")
+		if err != nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		defer file.Close()
+		serveStatic(c, fs, name, file, info, StaticConfig{}, etags)
+	})
+}
+
+var errIsDir = fmt.Errorf("goo: is a directory")
+
+// openStatic opens name, or the index file inside it when name is a directory
+func openStatic(fs http.FileSystem, name string, index string) (http.File, os.FileInfo, error) {
+	file, err := fs.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	if !info.IsDir() {
+		return file, info, nil
+	}
+	file.Close()
+	if index == "" {
+		return nil, nil, errIsDir
+	}
+	file, info, err = openStatic(fs, path.Join(name, index), "")
+	if err != nil {
+		return nil, nil, errIsDir
+	}
+	return file, info, nil
+}
+
+func serveStatic(c *Context, fs http.FileSystem, name string, file http.File, info os.FileInfo, config StaticConfig, etags *staticETags) {
+	if value := cacheControl(config.CacheControl, name); value != "" {
+		c.SetHeader("Cache-Control", value)
+	}
+
+	if config.Gzip {
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		if strings.Contains(c.Req.Header.Get("Accept-Encoding"), "gzip") {
+			if gz, gzInfo, err := openStatic(fs, name+".gz", ""); err == nil {
+				defer gz.Close()
+				contentType := mime.TypeByExtension(path.Ext(name))
+				if contentType == "" {
+					contentType = "application/octet-stream"
+				}
+				c.SetHeader("Content-Type", contentType)
+				c.SetHeader("Content-Encoding", "gzip")
+				c.SetHeader("ETag", etags.get(name+".gz", gz, gzInfo, "-gz"))
+				http.ServeContent(c.Writer, c.Req, info.Name(), gzInfo.ModTime(), gz)
+				return
+			}
+		}
+	}
+
+	c.SetHeader("ETag", etags.get(name, file, info, ""))
+	http.ServeContent(c.Writer, c.Req, info.Name(), info.ModTime(), file)
+}
+
+// staticETags caches the content hashes of files without a modification
+// time, like those of an embed.FS, which don't change while running
+type staticETags struct {
+	hashes sync.Map // name -> etag
+}
+
+// get builds a weak validator from the file size and modification time,
+// or from the content when the modification time is unknown
+func (e *staticETags) get(name string, file http.File, info os.FileInfo, suffix string) string {
+	if !info.ModTime().IsZero() {
+		return fmt.Sprintf(`W/"%x-%x%s"`, info.Size(), info.ModTime().UnixNano(), suffix)
+	}
+	if tag, ok := e.hashes.Load(name); ok {
+		return tag.(string)
+	}
+	sum := sha256.New()
+	_, err := io.Copy(sum, file)
+	if _, seekErr := file.Seek(0, io.SeekStart); err != nil || seekErr != nil {
+		return fmt.Sprintf(`W/"%x%s"`, info.Size(), suffix)
+	}
+	tag := fmt.Sprintf(`W/"%x%s"`, sum.Sum(nil)[:16], suffix)
+	e.hashes.Store(name, tag)
+	return tag
+}
+
+func cacheControl(policies map[string]string, name string) string {
+	var value string
+	longest := -1
+	for prefix, v := range policies {
+		if strings.HasPrefix(name, prefix) && len(prefix) > longest {
+			value, longest = v, len(prefix)
+		}
+	}
+	return value
+}
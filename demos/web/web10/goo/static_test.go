@@ -0,0 +1,98 @@
+package goo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func newStaticTestEngine(config StaticConfig) *Engine {
+	fsys := fstest.MapFS{
+		"index.html":      {Data: []byte("index"), ModTime: time.Unix(1000, 0)},
+		"todo/index.html": {Data: []byte("todo index")},
+		"css/app.css":     {Data: []byte("body{}"), ModTime: time.Unix(1000, 0)},
+		"css/app.css.gz":  {Data: []byte("gzipped"), ModTime: time.Unix(1000, 0)},
+		"empty/readme.md": {Data: []byte("readme")},
+	}
+	r := New()
+	r.StaticWithConfig("/assets", http.FS(fsys), config)
+	return r
+}
+
+func serveStaticTest(r *Engine, path string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestStaticIndexAndListing(t *testing.T) {
+	r := newStaticTestEngine(defaultStaticConfig)
+	if w := serveStaticTest(r, "/assets/todo", nil); w.Code != http.StatusOK || w.Body.String() != "todo index" {
+		t.Fatalf("directory should serve index.html, got %d %q", w.Code, w.Body.String())
+	}
+	if w := serveStaticTest(r, "/assets/empty", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("directory listing should be disabled, got %d", w.Code)
+	}
+
+	r = newStaticTestEngine(StaticConfig{Fallback: "index.html"})
+	if w := serveStaticTest(r, "/assets/todo/1/edit", nil); w.Code != http.StatusOK || w.Body.String() != "index" {
+		t.Fatalf("missing file should fall back to index.html, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestStaticCaching(t *testing.T) {
+	r := newStaticTestEngine(StaticConfig{
+		CacheControl: map[string]string{"/": "no-cache", "/css/": "max-age=3600"},
+	})
+	w := serveStaticTest(r, "/assets/css/app.css", nil)
+	if w.Header().Get("Cache-Control") != "max-age=3600" {
+		t.Fatalf("unexpected Cache-Control %q", w.Header().Get("Cache-Control"))
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" || w.Header().Get("Last-Modified") == "" {
+		t.Fatal("ETag and Last-Modified should be set")
+	}
+
+	w = serveStaticTest(r, "/assets/css/app.css", http.Header{"If-None-Match": {etag}})
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("matching ETag should be 304, got %d", w.Code)
+	}
+}
+
+func TestStaticGzip(t *testing.T) {
+	r := newStaticTestEngine(StaticConfig{Gzip: true})
+	w := serveStaticTest(r, "/assets/css/app.css", http.Header{"Accept-Encoding": {"gzip, deflate"}})
+	if w.Body.String() != "gzipped" || w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("gzip sidecar should be served, got %q", w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != "text/css; charset=utf-8" {
+		t.Fatalf("unexpected Content-Type %q", w.Header().Get("Content-Type"))
+	}
+
+	w = serveStaticTest(r, "/assets/css/app.css", nil)
+	if w.Body.String() != "body{}" || w.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("plain file should be served, got %q", w.Body.String())
+	}
+}
+
+func TestStaticETagWithoutModTime(t *testing.T) {
+	serve := func(content string, header http.Header) *httptest.ResponseRecorder {
+		r := New()
+		r.StaticFS("/assets", fstest.MapFS{"app.js": {Data: []byte(content)}})
+		return serveStaticTest(r, "/assets/app.js", header)
+	}
+	etag := serve("v1()", nil).Header().Get("ETag")
+	if w := serve("v1()", http.Header{"If-None-Match": {etag}}); w.Code != http.StatusNotModified {
+		t.Fatalf("unchanged file should be 304, got %d", w.Code)
+	}
+	// redeployed with the same size
+	if w := serve("v2()", http.Header{"If-None-Match": {etag}}); w.Code != http.StatusOK || w.Body.String() != "v2()" {
+		t.Fatalf("changed file should be sent, got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -10,13 +10,14 @@ type node struct {
 	part     string
 	children []*node
 	isWild   bool
+	match    func(string) bool // constraint of a :param<constraint> part
 }
 
 func (n *node) String() string {
 	return fmt.Sprintf("node{pattern=%s, part=%s, isWild=%t}", n.pattern, n.part, n.isWild)
 }
 
-func (n *node) insert(pattern string, parts []string, height int) {
+func (n *node) insert(pattern string, parts []string, height int, matchers map[string]func(string) bool) {
 	if len(parts) == height {
 		n.pattern = pattern
 		return
@@ -25,10 +26,21 @@ func (n *node) insert(pattern string, parts []string, height int) {
 	part := parts[height]
 	child := n.matchChild(part)
 	if child == nil {
-		child = &node{part: part, isWild: part[0] == ':' || part[0] == '*'}
+		child = &node{part: part, isWild: part[0] == ':' || part[0] == '*', match: matchers[part]}
 		n.children = append(n.children, child)
 	}
-	child.insert(pattern, parts, height+1)
+	child.insert(pattern, parts, height+1, matchers)
+}
+
+// child returns the child inserted for part, unlike matchChild it
+// tells :id<int> and :id<uuid> apart
+func (n *node) child(part string) *node {
+	for _, child := range n.children {
+		if child.part == part {
+			return child
+		}
+	}
+	return nil
 }
 
 func (n *node) search(parts []string, height int) *node {
@@ -61,9 +73,11 @@ func (n *node) travel(list *([]*node)) {
 	}
 }
 
+// matchChild finds the child to insert part into, constrained params
+// get their own node so they can fall through to their siblings
 func (n *node) matchChild(part string) *node {
 	for _, child := range n.children {
-		if child.part == part || child.isWild {
+		if child.part == part || child.isWild && child.match == nil && !hasConstraint(part) {
 			return child
 		}
 	}
@@ -73,9 +87,37 @@ func (n *node) matchChild(part string) *node {
 func (n *node) matchChildren(part string) []*node {
 	nodes := make([]*node, 0)
 	for _, child := range n.children {
-		if child.part == part || child.isWild {
+		if child.part == part || child.isWild && (child.match == nil || child.match(part)) {
 			nodes = append(nodes, child)
 		}
 	}
 	return nodes
 }
+
+// searchFold is search ignoring the case of static parts, it returns
+// the parts with the static ones spelled as registered
+func (n *node) searchFold(parts []string, height int) ([]string, bool) {
+	if len(parts) == height || strings.HasPrefix(n.part, "*") {
+		if n.pattern == "" {
+			return nil, false
+		}
+		return parts[height:], true
+	}
+
+	part := parts[height]
+	for _, child := range n.children {
+		fixed := part
+		if !child.isWild {
+			if !strings.EqualFold(child.part, part) {
+				continue
+			}
+			fixed = child.part
+		} else if child.match != nil && !child.match(part) {
+			continue
+		}
+		if rest, ok := child.searchFold(parts, height+1); ok {
+			return append([]string{fixed}, rest...), true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,182 @@
+package goo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const defaultMultipartMemory = 32 << 20 // 32 MB, same as net/http
+
+// ErrFileType is returned for uploaded files whose sniffed
+// content type isn't allowed by UploadLimit
+var ErrFileType = errors.New("goo: file type not allowed")
+
+// UploadConfig limits multipart uploads, see UploadLimit
+type UploadConfig struct {
+	// MaxBodySize rejects larger request bodies with 413, 0 is unlimited
+	MaxBodySize int64
+	// AllowedTypes are the content types sniffed from uploaded files that
+	// are accepted, "image/" allows every image. Empty allows everything
+	AllowedTypes []string
+}
+
+// UploadLimit enforces config for the upload helpers of the handlers behind it
+func UploadLimit(config UploadConfig) HandlerFunc {
+	return func(c *Context) {
+		if config.MaxBodySize > 0 {
+			if c.Req.ContentLength > config.MaxBodySize {
+				c.Fail(http.StatusRequestEntityTooLarge, "request body too large")
+				return
+			}
+			c.Req.Body = http.MaxBytesReader(c.Writer, c.Req.Body, config.MaxBodySize)
+		}
+		c.uploadTypes = config.AllowedTypes
+		c.Next()
+	}
+}
+
+func (c *Context) checkFileType(contentType string) error {
+	if len(c.uploadTypes) == 0 {
+		return nil
+	}
+	contentType = mediaType(contentType)
+	for _, allowed := range c.uploadTypes {
+		if contentType == allowed || strings.HasSuffix(allowed, "/") && strings.HasPrefix(contentType, allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrFileType, contentType)
+}
+
+func sniffFileHeader(file *multipart.FileHeader) (string, error) {
+	f, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// MultipartForm parses the multipart form, keeping up to
+// Engine.MaxMultipartMemory bytes in memory and the rest in temp files
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	if c.Req.MultipartForm == nil {
+		if err := c.Req.ParseMultipartForm(c.engine.MaxMultipartMemory); err != nil {
+			return nil, err
+		}
+	}
+	if len(c.uploadTypes) > 0 {
+		for _, files := range c.Req.MultipartForm.File {
+			for _, file := range files {
+				contentType, err := sniffFileHeader(file)
+				if err != nil {
+					return nil, err
+				}
+				if err := c.checkFileType(contentType); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return c.Req.MultipartForm, nil
+}
+
+// FormFile returns the first file uploaded under name
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+	files := form.File[name]
+	if len(files) == 0 {
+		return nil, http.ErrMissingFile
+	}
+	return files[0], nil
+}
+
+// SaveUploadedFile writes the uploaded file to dst, creating its directory
+func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Part is one part of a streamed multipart body
+type Part struct {
+	*multipart.Part
+	// ContentType is sniffed from the content of file parts
+	ContentType string
+	reader      io.Reader
+}
+
+func (p *Part) Read(b []byte) (int, error) {
+	return p.reader.Read(b)
+}
+
+// EachPart streams the multipart body part by part without buffering it,
+// fn must consume the part before returning. Iteration stops at the first
+// error, which is returned
+func (c *Context) EachPart(fn func(part *Part) error) error {
+	reader, err := c.Req.MultipartReader()
+	if err != nil {
+		return err
+	}
+	for {
+		p, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		part := &Part{Part: p, reader: p}
+		if p.FileName() != "" {
+			buffered := bufio.NewReaderSize(p, 512)
+			head, err := buffered.Peek(512)
+			if err != nil && err != io.EOF {
+				p.Close()
+				return err
+			}
+			part.ContentType = http.DetectContentType(head)
+			part.reader = buffered
+			if err := c.checkFileType(part.ContentType); err != nil {
+				p.Close()
+				return err
+			}
+		}
+
+		err = fn(part)
+		p.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package goo
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n0000")
+
+func newUploadRequest(t *testing.T, files map[string][]byte) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("title", "avatar")
+	for name, content := range files {
+		fw, err := mw.CreateFormFile(name, name+".bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(content)
+	}
+	mw.Close()
+	req := httptest.NewRequest("POST", "/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestUpload(t *testing.T) {
+	dir := t.TempDir()
+	r := New()
+	r.Use(UploadLimit(UploadConfig{MaxBodySize: 1024, AllowedTypes: []string{"image/"}}))
+	r.POST("/upload", func(c *Context) {
+		file, err := c.FormFile("avatar")
+		if errors.Is(err, ErrFileType) {
+			c.Fail(http.StatusUnsupportedMediaType, err.Error())
+			return
+		}
+		if err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := c.SaveUploadedFile(file, filepath.Join(dir, "avatars", file.Filename)); err != nil {
+			c.Fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.String(http.StatusOK, "%s", c.PostForm("title"))
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, newUploadRequest(t, map[string][]byte{"avatar": pngHeader}))
+	if w.Code != http.StatusOK || w.Body.String() != "avatar" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	if saved, _ := os.ReadFile(filepath.Join(dir, "avatars", "avatar.bin")); !bytes.Equal(saved, pngHeader) {
+		t.Fatalf("unexpected saved file %q", saved)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, newUploadRequest(t, map[string][]byte{"avatar": []byte("plain text")}))
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("text file should be rejected, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, newUploadRequest(t, map[string][]byte{"avatar": bytes.Repeat(pngHeader, 200)}))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("large body should be rejected, got %d", w.Code)
+	}
+}
+
+func TestEachPart(t *testing.T) {
+	r := New()
+	r.POST("/upload", func(c *Context) {
+		var names []string
+		err := c.EachPart(func(part *Part) error {
+			content, err := io.ReadAll(part)
+			names = append(names, part.FormName()+":"+part.ContentType+":"+string(content))
+			return err
+		})
+		if err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, names)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, newUploadRequest(t, map[string][]byte{"notes": []byte("hello")}))
+	if w.Body.String() != `["title::avatar","notes:text/plain; charset=utf-8:hello"]`+"\n" {
+		t.Fatalf("unexpected parts %q", w.Body.String())
+	}
+}
@@ -0,0 +1,546 @@
+package goo
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// WebSocket message types, see https://www.rfc-editor.org/rfc/rfc6455
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// WebSocket close codes
+const (
+	CloseNormalClosure   = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	CloseNoStatus        = 1005
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+)
+
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocketConfig controls Context.Upgrade, see Engine.SetWebSocketConfig
+type WebSocketConfig struct {
+	// CheckOrigin accepts the handshake, the default only allows
+	// requests without Origin or from the same host
+	CheckOrigin func(r *http.Request) bool
+	// Subprotocols supported by the server in order of preference
+	Subprotocols []string
+	// MaxMessageSize closes the connection with 1009 when a message
+	// is larger, 0 means 1MB
+	MaxMessageSize int64
+	// FragmentSize splits written messages into frames of this size, 0 never splits
+	FragmentSize int
+	// PingInterval sends a ping that must be answered within another
+	// interval, 0 disables the keepalive
+	PingInterval time.Duration
+	// WriteTimeout bounds every frame write, 0 means 10s
+	WriteTimeout time.Duration
+}
+
+// SetWebSocketConfig changes how Context.Upgrade handles connections
+func (engine *Engine) SetWebSocketConfig(config WebSocketConfig) {
+	engine.webSocketConfig = config
+}
+
+// CloseError is returned by ReadMessage once the connection is closed
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Text)
+}
+
+// WebSocket is an upgraded connection. One goroutine may read while
+// others write, writes are serialized
+type WebSocket struct {
+	conn        net.Conn
+	reader      *bufio.Reader
+	config      WebSocketConfig
+	Subprotocol string
+
+	writeMu   sync.Mutex
+	closeOnce sync.Once
+	closeSent bool
+	done      chan struct{}
+}
+
+// Upgrade switches the request to the WebSocket protocol. On failure
+// the error response has already been written
+func (c *Context) Upgrade() (*WebSocket, error) {
+	config := c.engine.webSocketConfig
+	fail := func(code int, message string) (*WebSocket, error) {
+		c.Fail(code, message)
+		return nil, errors.New("websocket: " + message)
+	}
+
+	req := c.Req
+	if req.Method != http.MethodGet {
+		return fail(http.StatusMethodNotAllowed, "handshake must use GET")
+	}
+	if !headerContains(req.Header, "Connection", "upgrade") || !headerContains(req.Header, "Upgrade", "websocket") {
+		return fail(http.StatusBadRequest, "not a websocket handshake")
+	}
+	if req.Header.Get("Sec-WebSocket-Version") != "13" {
+		c.SetHeader("Sec-WebSocket-Version", "13")
+		return fail(http.StatusUpgradeRequired, "unsupported websocket version")
+	}
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return fail(http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+	}
+	checkOrigin := config.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(req) {
+		return fail(http.StatusForbidden, "origin not allowed")
+	}
+
+	hijacker, ok := c.Writer.(http.Hijacker)
+	if !ok {
+		return fail(http.StatusInternalServerError, "response writer can't be hijacked")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return fail(http.StatusInternalServerError, err.Error())
+	}
+
+	ws := &WebSocket{
+		conn:        conn,
+		reader:      rw.Reader,
+		config:      config,
+		Subprotocol: selectSubprotocol(req, config.Subprotocols),
+		done:        make(chan struct{}),
+	}
+	if ws.config.MaxMessageSize <= 0 {
+		ws.config.MaxMessageSize = 1 << 20
+	}
+	if ws.config.WriteTimeout <= 0 {
+		ws.config.WriteTimeout = 10 * time.Second
+	}
+
+	var response strings.Builder
+	response.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	response.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+	if ws.Subprotocol != "" {
+		response.WriteString("Sec-WebSocket-Protocol: " + ws.Subprotocol + "\r\n")
+	}
+	response.WriteString("\r\n")
+	conn.SetWriteDeadline(time.Now().Add(ws.config.WriteTimeout))
+	if _, err := io.WriteString(conn, response.String()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetWriteDeadline(time.Time{})
+	c.StatusCode = http.StatusSwitchingProtocols
+
+	if ws.config.PingInterval > 0 {
+		conn.SetReadDeadline(time.Now().Add(2 * ws.config.PingInterval))
+		go ws.keepalive()
+	}
+	return ws, nil
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + webSocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func headerContains(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, v := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+func selectSubprotocol(r *http.Request, supported []string) string {
+	var requested []string
+	for _, value := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, v := range strings.Split(value, ",") {
+			requested = append(requested, strings.TrimSpace(v))
+		}
+	}
+	for _, s := range supported {
+		for _, r := range requested {
+			if s == r {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+func (ws *WebSocket) keepalive() {
+	ticker := time.NewTicker(ws.config.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := ws.writeFrame(true, PingMessage, nil); err != nil {
+				ws.conn.Close()
+				return
+			}
+		case <-ws.done:
+			return
+		}
+	}
+}
+
+// RemoteAddr returns the address of the peer
+func (ws *WebSocket) RemoteAddr() net.Addr {
+	return ws.conn.RemoteAddr()
+}
+
+type frame struct {
+	fin     bool
+	opcode  int
+	payload []byte
+}
+
+func (ws *WebSocket) readFrame(limit int64) (frame, error) {
+	var f frame
+	var header [2]byte
+	if _, err := io.ReadFull(ws.reader, header[:]); err != nil {
+		return f, err
+	}
+	if ws.config.PingInterval > 0 {
+		ws.conn.SetReadDeadline(time.Now().Add(2 * ws.config.PingInterval))
+	}
+
+	f.fin = header[0]&0x80 != 0
+	f.opcode = int(header[0] & 0x0f)
+	if header[0]&0x70 != 0 {
+		return f, &CloseError{CloseProtocolError, "reserved bits set"}
+	}
+	if header[1]&0x80 == 0 {
+		return f, &CloseError{CloseProtocolError, "client frames must be masked"}
+	}
+
+	length := int64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(ws.reader, ext[:]); err != nil {
+			return f, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(ws.reader, ext[:]); err != nil {
+			return f, err
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+
+	if f.opcode >= CloseMessage {
+		if !f.fin || length > 125 {
+			return f, &CloseError{CloseProtocolError, "invalid control frame"}
+		}
+	} else if length < 0 || length > limit {
+		return f, &CloseError{CloseMessageTooBig, "message too big"}
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(ws.reader, mask[:]); err != nil {
+		return f, err
+	}
+	f.payload = make([]byte, length)
+	if _, err := io.ReadFull(ws.reader, f.payload); err != nil {
+		return f, err
+	}
+	for i := range f.payload {
+		f.payload[i] ^= mask[i%4]
+	}
+	return f, nil
+}
+
+// ReadMessage returns the next text or binary message, reassembling
+// fragments and answering pings and close frames on the way
+func (ws *WebSocket) ReadMessage() (int, []byte, error) {
+	messageType := 0
+	var message []byte
+	for {
+		f, err := ws.readFrame(ws.config.MaxMessageSize - int64(len(message)))
+		if err != nil {
+			return 0, nil, ws.fail(err)
+		}
+
+		switch f.opcode {
+		case PingMessage:
+			if err := ws.writeFrame(true, PongMessage, f.payload); err != nil {
+				return 0, nil, ws.fail(err)
+			}
+			continue
+		case PongMessage:
+			continue
+		case CloseMessage:
+			return 0, nil, ws.closeReceived(f.payload)
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, ws.fail(&CloseError{CloseProtocolError, "expected continuation frame"})
+			}
+			messageType = f.opcode
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, ws.fail(&CloseError{CloseProtocolError, "unexpected continuation frame"})
+			}
+		default:
+			return 0, nil, ws.fail(&CloseError{CloseProtocolError, "unknown opcode"})
+		}
+
+		message = append(message, f.payload...)
+		if f.fin {
+			if messageType == TextMessage && !utf8.Valid(message) {
+				return 0, nil, ws.fail(&CloseError{CloseInvalidPayload, "invalid utf-8"})
+			}
+			return messageType, message, nil
+		}
+	}
+}
+
+// ReadJSON reads the next message and decodes it into v
+func (ws *WebSocket) ReadJSON(v interface{}) error {
+	_, data, err := ws.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// closeReceived answers the peer's close frame and closes the connection
+func (ws *WebSocket) closeReceived(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatus}
+	if len(payload) >= 2 {
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Text = string(payload[2:])
+	}
+	echo := closeErr.Code
+	if echo == CloseNoStatus {
+		echo = CloseNormalClosure
+	}
+	ws.writeClose(echo, "")
+	ws.closeConn()
+	return closeErr
+}
+
+// fail closes the connection, sending the close code when err is a protocol error
+func (ws *WebSocket) fail(err error) error {
+	var closeErr *CloseError
+	if errors.As(err, &closeErr) {
+		ws.writeClose(closeErr.Code, closeErr.Text)
+	}
+	ws.closeConn()
+	return err
+}
+
+func (ws *WebSocket) writeFrame(fin bool, opcode int, payload []byte) error {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+	return ws.writeFrameLocked(fin, opcode, payload)
+}
+
+func (ws *WebSocket) writeFrameLocked(fin bool, opcode int, payload []byte) error {
+	if ws.closeSent {
+		return &CloseError{CloseNormalClosure, "close already sent"}
+	}
+	if opcode == CloseMessage {
+		ws.closeSent = true
+	}
+
+	header := make([]byte, 2, 10)
+	if fin {
+		header[0] = 0x80
+	}
+	header[0] |= byte(opcode)
+	switch length := len(payload); {
+	case length < 126:
+		header[1] = byte(length)
+	case length <= 0xffff:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+
+	ws.conn.SetWriteDeadline(time.Now().Add(ws.config.WriteTimeout))
+	_, err := (&net.Buffers{header, payload}).WriteTo(ws.conn)
+	return err
+}
+
+// WriteMessage sends a text or binary message, split into
+// fragments when FragmentSize is configured
+func (ws *WebSocket) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return ws.writeFrame(true, messageType, data)
+	}
+	size := ws.config.FragmentSize
+	if size <= 0 || len(data) <= size {
+		return ws.writeFrame(true, messageType, data)
+	}
+
+	// hold the lock between fragments so other messages can't interleave
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+	opcode := messageType
+	for len(data) > 0 {
+		n := size
+		if n > len(data) {
+			n = len(data)
+		}
+		if err := ws.writeFrameLocked(n == len(data), opcode, data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+		opcode = continuationFrame
+	}
+	return nil
+}
+
+// WriteJSON sends v as a json text message
+func (ws *WebSocket) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ws.WriteMessage(TextMessage, data)
+}
+
+func (ws *WebSocket) writeClose(code int, text string) error {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	if len(text) > 123 {
+		text = text[:123]
+	}
+	return ws.writeFrame(true, CloseMessage, append(payload, text...))
+}
+
+// Close starts the close handshake, the connection is dropped when the
+// peer answers through ReadMessage or after a second
+func (ws *WebSocket) Close(code int, text string) error {
+	err := ws.writeClose(code, text)
+	time.AfterFunc(time.Second, ws.closeConn)
+	return err
+}
+
+func (ws *WebSocket) closeConn() {
+	ws.closeOnce.Do(func() {
+		close(ws.done)
+		ws.conn.Close()
+	})
+}
+
+// Hub groups connections into rooms to broadcast messages to them
+type Hub struct {
+	mu    sync.RWMutex
+	rooms map[string]map[*WebSocket]bool
+}
+
+func NewHub() *Hub {
+	return &Hub{rooms: make(map[string]map[*WebSocket]bool)}
+}
+
+// Join adds ws to room
+func (h *Hub) Join(room string, ws *WebSocket) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.rooms[room] == nil {
+		h.rooms[room] = make(map[*WebSocket]bool)
+	}
+	h.rooms[room][ws] = true
+}
+
+// Leave removes ws from room
+func (h *Hub) Leave(room string, ws *WebSocket) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.leave(room, ws)
+}
+
+func (h *Hub) leave(room string, ws *WebSocket) {
+	delete(h.rooms[room], ws)
+	if len(h.rooms[room]) == 0 {
+		delete(h.rooms, room)
+	}
+}
+
+// LeaveAll removes ws from every room, call it when the connection ends
+func (h *Hub) LeaveAll(ws *WebSocket) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for room := range h.rooms {
+		h.leave(room, ws)
+	}
+}
+
+// Count returns the number of connections in room
+func (h *Hub) Count(room string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.rooms[room])
+}
+
+// Broadcast sends the message to every connection in room,
+// connections that fail to receive it are closed and removed
+func (h *Hub) Broadcast(room string, messageType int, data []byte) {
+	h.mu.RLock()
+	members := make([]*WebSocket, 0, len(h.rooms[room]))
+	for ws := range h.rooms[room] {
+		members = append(members, ws)
+	}
+	h.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, ws := range members {
+		wg.Add(1)
+		go func(ws *WebSocket) {
+			defer wg.Done()
+			if err := ws.WriteMessage(messageType, data); err != nil {
+				ws.closeConn()
+				h.LeaveAll(ws)
+			}
+		}(ws)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,176 @@
+package goo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialTestWebSocket(t *testing.T, server *httptest.Server, header string) (*testClient, string) {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: "+strings.TrimPrefix(server.URL, "http://")+"\r\n"+
+		"Connection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Version: 13\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"+header+"\r\n")
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testClient{conn, reader}, resp.Status + " " + resp.Header.Get("Sec-WebSocket-Accept")
+}
+
+func (tc *testClient) writeFrame(fin bool, opcode byte, payload []byte) {
+	header := []byte{opcode, 0x80 | byte(len(payload))}
+	if fin {
+		header[0] |= 0x80
+	}
+	mask := []byte{1, 2, 3, 4}
+	masked := make([]byte, len(payload))
+	for i := range payload {
+		masked[i] = payload[i] ^ mask[i%4]
+	}
+	tc.conn.Write(append(append(header, mask...), masked...))
+}
+
+func (tc *testClient) readFrame(t *testing.T) (byte, []byte) {
+	var header [2]byte
+	if _, err := io.ReadFull(tc.reader, header[:]); err != nil {
+		t.Fatal(err)
+	}
+	payload := make([]byte, header[1]&0x7f)
+	if _, err := io.ReadFull(tc.reader, payload); err != nil {
+		t.Fatal(err)
+	}
+	return header[0] & 0x0f, payload
+}
+
+func newEchoServer() *httptest.Server {
+	r := New()
+	r.Use(Logger())
+	r.SetWebSocketConfig(WebSocketConfig{MaxMessageSize: 64})
+	r.GET("/ws", func(c *Context) {
+		ws, err := c.Upgrade()
+		if err != nil {
+			return
+		}
+		for {
+			messageType, data, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			ws.WriteMessage(messageType, data)
+		}
+	})
+	return httptest.NewServer(r)
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	server := newEchoServer()
+	defer server.Close()
+
+	client, status := dialTestWebSocket(t, server, "")
+	defer client.conn.Close()
+	if status != "101 Switching Protocols s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected handshake %q", status)
+	}
+
+	// a fragmented message with a ping in between
+	client.writeFrame(false, TextMessage, []byte("hello "))
+	client.writeFrame(true, PingMessage, []byte("p"))
+	client.writeFrame(true, continuationFrame, []byte("goo"))
+
+	if op, payload := client.readFrame(t); op != PongMessage || string(payload) != "p" {
+		t.Fatalf("expected pong, got %d %q", op, payload)
+	}
+	if op, payload := client.readFrame(t); op != TextMessage || string(payload) != "hello goo" {
+		t.Fatalf("expected echo, got %d %q", op, payload)
+	}
+
+	client.writeFrame(true, CloseMessage, binary.BigEndian.AppendUint16(nil, CloseNormalClosure))
+	if op, payload := client.readFrame(t); op != CloseMessage || binary.BigEndian.Uint16(payload) != CloseNormalClosure {
+		t.Fatalf("expected close, got %d %q", op, payload)
+	}
+}
+
+func TestWebSocketLimits(t *testing.T) {
+	server := newEchoServer()
+	defer server.Close()
+
+	client, _ := dialTestWebSocket(t, server, "")
+	defer client.conn.Close()
+	client.writeFrame(true, BinaryMessage, make([]byte, 100))
+	if op, payload := client.readFrame(t); op != CloseMessage || binary.BigEndian.Uint16(payload) != CloseMessageTooBig {
+		t.Fatalf("expected close 1009, got %d %q", op, payload)
+	}
+
+	_, status := dialTestWebSocket(t, server, "Origin: http://evil.example\r\n")
+	if !strings.HasPrefix(status, "403") {
+		t.Fatalf("cross origin handshake should be rejected, got %q", status)
+	}
+}
+
+func TestHub(t *testing.T) {
+	hub := NewHub()
+	joined := make(chan *WebSocket)
+	r := New()
+	r.GET("/ws", func(c *Context) {
+		ws, err := c.Upgrade()
+		if err != nil {
+			return
+		}
+		hub.Join("lobby", ws)
+		joined <- ws
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	alice, _ := dialTestWebSocket(t, server, "")
+	defer alice.conn.Close()
+	aliceWS := <-joined
+	bob, _ := dialTestWebSocket(t, server, "")
+	defer bob.conn.Close()
+	bobWS := <-joined
+	if hub.Count("lobby") != 2 {
+		t.Fatalf("unexpected count %d", hub.Count("lobby"))
+	}
+
+	hub.Broadcast("lobby", TextMessage, []byte("hello room"))
+	for _, client := range []*testClient{alice, bob} {
+		if op, payload := client.readFrame(t); op != TextMessage || string(payload) != "hello room" {
+			t.Fatalf("expected broadcast, got %d %q", op, payload)
+		}
+	}
+
+	// a member that can't receive is removed
+	bobWS.closeConn()
+	hub.Broadcast("lobby", TextMessage, []byte("bye"))
+	if hub.Count("lobby") != 1 {
+		t.Fatalf("unexpected count %d", hub.Count("lobby"))
+	}
+	if op, payload := alice.readFrame(t); op != TextMessage || string(payload) != "bye" {
+		t.Fatalf("expected broadcast, got %d %q", op, payload)
+	}
+
+	hub.Join("news", aliceWS)
+	hub.Leave("lobby", aliceWS)
+	if hub.Count("lobby") != 0 || hub.Count("news") != 1 {
+		t.Fatalf("unexpected counts %d %d", hub.Count("lobby"), hub.Count("news"))
+	}
+	hub.LeaveAll(aliceWS)
+	if hub.Count("news") != 0 {
+		t.Fatalf("unexpected count %d", hub.Count("news"))
+	}
+}
@@ -0,0 +1,142 @@
+package goo
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// responseWriter is the writer of every context, it remembers whether
+// the response was started so errors aren't rendered after it
+type responseWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	w.written = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseWriter) Written() bool {
+	return w.written
+}
+
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.written = true
+		f.Flush()
+	}
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		w.written = true
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("goo: response writer can't be hijacked")
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// statusWriter records the status written by a wrapped http.Handler into the context
+type statusWriter struct {
+	http.ResponseWriter
+	c *Context
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.c.StatusCode = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.c.StatusCode == 0 {
+		w.c.StatusCode = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("goo: response writer can't be hijacked")
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// WrapH turns a stdlib http.Handler into a goo HandlerFunc
+func WrapH(h http.Handler) HandlerFunc {
+	return func(c *Context) {
+		h.ServeHTTP(&statusWriter{c.Writer, c}, c.Req)
+	}
+}
+
+// WrapF turns a stdlib http.HandlerFunc into a goo HandlerFunc
+func WrapF(f http.HandlerFunc) HandlerFunc {
+	return WrapH(f)
+}
+
+// WrapMiddleware runs a stdlib func(http.Handler) http.Handler middleware
+// in a goo chain, the rest of the chain runs as its next handler with the
+// writer and request it passes on. The chain is aborted when the
+// middleware doesn't call next
+func WrapMiddleware(m func(http.Handler) http.Handler) HandlerFunc {
+	return func(c *Context) {
+		writer, req := c.Writer, c.Req
+		called := false
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			c.Writer, c.Req = w, r
+			c.Next()
+		})
+		m(next).ServeHTTP(&statusWriter{writer, c}, req)
+		c.Writer, c.Req = writer, req
+		if !called {
+			c.Abort()
+		}
+	}
+}
+
+var mountMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
+}
+
+// Mount delegates every request under prefix to h, which sees the
+// path with the prefix stripped. h may be another goo.Engine
+func (group *RouterGroup) Mount(prefix string, h http.Handler) {
+	prefix = path.Join("/", prefix)
+	absolutePrefix := path.Join("/", group.prefix, prefix)
+	handler := func(c *Context) {
+		req := c.Req.Clone(c.Req.Context())
+		req.URL.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(c.Req.URL.Path, absolutePrefix), "/")
+		if c.Req.URL.RawPath != "" {
+			req.URL.RawPath = "/" + strings.TrimPrefix(strings.TrimPrefix(c.Req.URL.RawPath, absolutePrefix), "/")
+		}
+		h.ServeHTTP(&statusWriter{c.Writer, c}, req)
+	}
+	for _, method := range mountMethods {
+		group.addRoute(method, prefix, handler)
+		group.addRoute(method, path.Join(prefix, "/*path"), handler)
+	}
+}
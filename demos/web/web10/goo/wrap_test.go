@@ -0,0 +1,102 @@
+package goo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMount(t *testing.T) {
+	sub := New()
+	sub.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "sub index")
+	})
+	sub.GET("/todo/:id", func(c *Context) {
+		c.String(http.StatusOK, "sub todo %s", c.Param("id"))
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("pong " + r.URL.Path))
+	})
+
+	r := New()
+	r.Group("/v1").Mount("/sub", sub)
+	r.Mount("/std", mux)
+	r.GET("/wrapped", WrapF(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	tests := map[string]string{
+		"/v1/sub":        "sub index",
+		"/v1/sub/todo/3": "sub todo 3",
+		"/std/ping":      "pong /ping",
+	}
+	for path, want := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Body.String() != want {
+			t.Errorf("%s = %q, want %q", path, w.Body.String(), want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/wrapped", nil))
+	if w.Code != http.StatusAccepted {
+		t.Errorf("wrapped handler status = %d", w.Code)
+	}
+}
+
+func TestWrapMiddleware(t *testing.T) {
+	auth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("X-User", "gootutu")
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	r := New()
+	r.Use(WrapMiddleware(auth))
+	r.GET("/todo", func(c *Context) {
+		c.String(http.StatusOK, "todo")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/todo", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("middleware should stop the chain, got %d %q", w.Code, w.Body.String())
+	}
+
+	req := httptest.NewRequest("GET", "/todo", nil)
+	req.Header.Set("Authorization", "Bearer x")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Body.String() != "todo" || w.Header().Get("X-User") != "gootutu" {
+		t.Fatalf("unexpected response %q", w.Body.String())
+	}
+}
+
+func TestIsAborted(t *testing.T) {
+	r := New()
+	aborted := map[string]bool{}
+	r.Use(func(c *Context) {
+		c.Next()
+		aborted[c.Path] = c.IsAborted()
+	})
+	r.GET("/ok", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	r.GET("/fail", func(c *Context) {
+		c.Fail(http.StatusForbidden, "no")
+	})
+	for _, path := range []string{"/ok", "/fail"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	if aborted["/ok"] || !aborted["/fail"] {
+		t.Fatalf("unexpected aborted %v", aborted)
+	}
+}
@@ -0,0 +1,275 @@
+package goo
+
+import (
+	"bufio"
+	"encoding"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// encodeYAML writes a block style YAML document without any dependency.
+// Struct fields honour `yaml:"name,omitempty"` tags and default to the
+// lowercased field name, map keys are sorted
+func encodeYAML(w io.Writer, obj interface{}) error {
+	bw := bufio.NewWriter(w)
+	e := &yamlEncoder{w: bw}
+	v := reflect.ValueOf(obj)
+	if isYAMLScalar(v) {
+		bw.WriteString(e.scalar(v))
+		bw.WriteString("\n")
+	} else {
+		e.block(v, 0, false)
+	}
+	if e.err != nil {
+		return e.err
+	}
+	return bw.Flush()
+}
+
+type yamlEncoder struct {
+	w   *bufio.Writer
+	err error
+	// pointers, maps and slices being written, to stop at cycles
+	visiting map[yamlRef]bool
+}
+
+type yamlRef struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// visit marks the references leading to the value of v as being written,
+// it returns them for leave, or false when v refers to one of its parents
+func (e *yamlEncoder) visit(v reflect.Value) ([]yamlRef, bool) {
+	var refs []yamlRef
+	for v.IsValid() {
+		var ref yamlRef
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Map:
+			if v.IsNil() {
+				return refs, true
+			}
+			ref = yamlRef{v.Pointer(), v.Type(), 0}
+		case reflect.Slice:
+			if v.IsNil() {
+				return refs, true
+			}
+			ref = yamlRef{v.Pointer(), v.Type(), v.Len()}
+		case reflect.Interface:
+			v = v.Elem()
+			continue
+		default:
+			return refs, true
+		}
+		if e.visiting[ref] {
+			e.leave(refs)
+			return nil, false
+		}
+		if e.visiting == nil {
+			e.visiting = make(map[yamlRef]bool)
+		}
+		e.visiting[ref] = true
+		refs = append(refs, ref)
+		if v.Kind() != reflect.Ptr {
+			return refs, true
+		}
+		v = v.Elem()
+	}
+	return refs, true
+}
+
+func (e *yamlEncoder) leave(refs []yamlRef) {
+	for _, ref := range refs {
+		delete(e.visiting, ref)
+	}
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+func isYAMLScalar(v reflect.Value) bool {
+	v = indirect(v)
+	if !v.IsValid() || v.Type().Implements(textMarshalerType) {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		return v.Len() == 0
+	case reflect.Struct:
+		return len(yamlFields(v)) == 0
+	case reflect.Slice, reflect.Array:
+		return v.Len() == 0 || v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8
+	}
+	return true
+}
+
+type yamlField struct {
+	key   string
+	value reflect.Value
+}
+
+func yamlFields(v reflect.Value) []yamlField {
+	var fields []yamlField
+	switch v.Kind() {
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			fields = append(fields, yamlField{fmt.Sprint(key.Interface()), v.MapIndex(key)})
+		}
+		sort.Slice(fields, func(i, j int) bool { return fields[i].key < fields[j].key })
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(f.Name)
+			}
+			if opts == "omitempty" && v.Field(i).IsZero() {
+				continue
+			}
+			fields = append(fields, yamlField{name, v.Field(i)})
+		}
+	}
+	return fields
+}
+
+func (e *yamlEncoder) write(s string) {
+	if e.err == nil {
+		_, e.err = e.w.WriteString(s)
+	}
+}
+
+// block writes a mapping or sequence, inline continues the line
+// of a "- " sequence entry instead of indenting the first line
+func (e *yamlEncoder) block(v reflect.Value, indent int, inline bool) {
+	refs, ok := e.visit(v)
+	if !ok {
+		if e.err == nil {
+			e.err = fmt.Errorf("goo: yaml: cycle through %s", v.Type())
+		}
+		return
+	}
+	defer e.leave(refs)
+	v = indirect(v)
+	pad := strings.Repeat("  ", indent)
+	line := func() string {
+		if inline {
+			inline = false
+			return ""
+		}
+		return pad
+	}
+	switch v.Kind() {
+	case reflect.Map, reflect.Struct:
+		for _, f := range yamlFields(v) {
+			e.write(line() + e.scalar(reflect.ValueOf(f.key)) + ":")
+			if isYAMLScalar(f.value) {
+				e.write(" " + e.scalar(f.value) + "\n")
+			} else {
+				e.write("\n")
+				e.block(f.value, indent+1, false)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			e.write(line() + "- ")
+			if isYAMLScalar(v.Index(i)) {
+				e.write(e.scalar(v.Index(i)) + "\n")
+			} else {
+				e.block(v.Index(i), indent+1, true)
+			}
+		}
+	}
+}
+
+func (e *yamlEncoder) scalar(v reflect.Value) string {
+	v = indirect(v)
+	if !v.IsValid() {
+		return "null"
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, err := m.MarshalText()
+		if err != nil {
+			e.err = err
+			return ""
+		}
+		return yamlString(string(text))
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		switch {
+		case math.IsNaN(f):
+			return ".nan"
+		case math.IsInf(f, 1):
+			return ".inf"
+		case math.IsInf(f, -1):
+			return "-.inf"
+		}
+		return strconv.FormatFloat(f, 'g', -1, v.Type().Bits())
+	case reflect.String:
+		return yamlString(v.String())
+	case reflect.Map, reflect.Struct:
+		return "{}"
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 && v.Len() > 0 {
+			return yamlString(string(v.Bytes()))
+		}
+		return "[]"
+	}
+	return yamlString(fmt.Sprint(v.Interface()))
+}
+
+// yamlString quotes s unless it is a plain scalar that reads back as the
+// same string with YAML 1.1 and 1.2 readers. Numbers in any base, special
+// floats and timestamps start with a digit or a dot, so those are quoted
+func yamlString(s string) string {
+	switch strings.ToLower(s) {
+	case "", "~", "null", "true", "false", "yes", "no", "on", "off", "y", "n":
+		return strconv.Quote(s)
+	}
+	if s[0] >= '0' && s[0] <= '9' || s[0] == '.' {
+		return strconv.Quote(s)
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return strconv.Quote(s)
+	}
+	for i, r := range s {
+		plain := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+			r == '_' || r == '.' || r == '/' || (i > 0 && (r == '-' || r == ' '))
+		if !plain {
+			return strconv.Quote(s)
+		}
+	}
+	if strings.HasSuffix(s, " ") {
+		return strconv.Quote(s)
+	}
+	return s
+}
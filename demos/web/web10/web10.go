@@ -1,13 +1,20 @@
 package main
 
 import (
+	"embed"
 	"fmt"
 	"goo"
 	"html/template"
+	"io/fs"
 	"net/http"
 	"time"
 )
 
+// templates and static files are compiled into the binary
+//
+//go:embed templates static
+var assets embed.FS
+
 type student struct {
 	Name string
 	Age  int8
@@ -18,32 +25,42 @@ func FormatAsDate(t time.Time) string {
 	return fmt.Sprintf("%d-%02d-%02d", year, month, day)
 }
 
-func login(c *goo.Context) {
+func login(c *goo.Context) error {
 	fmt.Println("method:", c.Method) //取得請求的方法
 	if c.Method == "GET" {
 		c.HTML(http.StatusOK, "login.tmpl", nil)
-	} else {
-		//請求的是登入資料，那麼執行登入的邏輯判斷
-		fmt.Println("username:", c.PostForm("username"))
-		fmt.Println("password:", c.PostForm("password"))
+		return nil
+	}
+	//請求的是登入資料，那麼執行登入的邏輯判斷
+	username := c.PostForm("username")
+	if username == "" {
+		return &goo.ParamError{Source: "form", Key: "username", Type: "string"}
 	}
+	fmt.Println("username:", username)
+	fmt.Println("password:", c.PostForm("password"))
+	c.String(http.StatusOK, "hello %s\n", username)
+	return nil
 }
 
 func main() {
 	r := goo.New()
-	r.Use(goo.Logger())
+	r.Use(goo.Logger(), goo.Secure(goo.DefaultSecureConfig()))
 	r.SetFuncMap(template.FuncMap{
 		"FormatAsDate": FormatAsDate,
 	})
-	r.LoadHTMLGlob("templates/*")
-	r.Static("/assets", "./static")
+	r.LoadHTMLFS(assets, "templates/*")
+	static, _ := fs.Sub(assets, "static")
+	r.StaticFS("/assets", static).Named("assets")
 
 	stu1 := &student{Name: "gootutu", Age: 20}
 	stu2 := &student{Name: "Jack", Age: 22}
 	r.GET("/", func(c *goo.Context) {
 		c.HTML(http.StatusOK, "css.tmpl", nil)
 	})
-	r.GET("/students", func(c *goo.Context) {
+	// the student list rarely changes, serve it from memory
+	students := r.Group("/students")
+	students.Use(goo.NewCache(goo.CacheConfig{TTL: time.Minute}).Handler())
+	students.GET("", func(c *goo.Context) {
 		c.HTML(http.StatusOK, "arr.tmpl", goo.H{
 			"title":  "goo",
 			"stuArr": [2]*student{stu1, stu2},
@@ -57,8 +74,8 @@ func main() {
 		})
 	})
 
-	r.GET("/login", login)
-	r.POST("/login", login)
+	r.GETErr("/login", login).Named("login")
+	r.POSTErr("/login", login)
 
 	r.Run(":9999")
 }